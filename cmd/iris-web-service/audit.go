@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// auditCommand implements "iris-web-service audit verify"
+func auditCommand(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return errors.New("usage: iris-web-service audit verify [-config file] [-path audit.log]")
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	configFile := fs.String("config", "config.yml", "path to config file")
+	path := fs.String("path", "", "audit log to verify (defaults to audit.path from the config)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		cfg, err := config.Load(&config.Options{ConfigFile: *configFile})
+		if err != nil {
+			return err
+		}
+		*path = cfg.Audit.Path
+	}
+	if *path == "" {
+		return errors.New("no audit log configured; set audit.path or pass -path")
+	}
+
+	res, err := audit.Verify(*path)
+	if err != nil {
+		return fmt.Errorf("audit chain verification failed: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "audit chain OK: %d records in %d files\n", res.Records, res.Files)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func TestAuditVerifyCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(config.AuditConfig{Path: path})
+	if err != nil {
+		t.Fatalf("audit.Open() failed: %v", err)
+	}
+	l.Record(audit.Event{Action: audit.ActionGreeting, Actor: "ip:127.0.0.1"})
+	l.Close()
+
+	if err := auditCommand([]string{"verify", "-path", path}); err != nil {
+		t.Fatalf("audit verify failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), "127.0.0.1", "10.0.0.1", 1)), 0o600)
+
+	err = auditCommand([]string{"verify", "-path", path})
+	if err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("expected hash mismatch error, got %v", err)
+	}
+}
+
+func TestAuditCommandUsage(t *testing.T) {
+	if err := auditCommand(nil); err == nil {
+		t.Error("expected usage error without subcommand")
+	}
+	if err := auditCommand([]string{"verify", "-config", "nonexistent.yml"}); err == nil {
+		t.Error("expected error when no audit path is configured")
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"cmd", "bogus"}
+	if err := run(); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected unknown command error, got %v", err)
+	}
+}
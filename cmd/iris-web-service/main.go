@@ -1,7 +1,11 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/Elenetta17/iris-web-service/internal/config"
 )
@@ -12,8 +16,30 @@ func main() {
 	}
 }
 
+// run dispatches to a subcommand. Without one, or when the first argument
+// is a flag, the server is started for backwards compatibility
 func run() error {
-	opts := config.ParseFlags()
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(args)
+	}
+
+	switch args[0] {
+	case "serve":
+		return serve(args[1:])
+	case "audit":
+		return auditCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "train":
+		return trainCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func serve(args []string) error {
+	opts := config.ParseArgs(flag.CommandLine, args)
 
 	cfg, err := config.Load(opts)
 	if err != nil {
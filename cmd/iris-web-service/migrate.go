@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/storage"
+)
+
+const migrateUsage = "usage: iris-web-service migrate up|down|status [-config file] [-steps n]"
+
+// migrateCommand implements "iris-web-service migrate up|down|status"
+func migrateCommand(args []string) error {
+	return runMigrate(args, os.Stdout)
+}
+
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	action := args[0]
+	if action != "up" && action != "down" && action != "status" {
+		return errors.New(migrateUsage)
+	}
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	configFile := fs.String("config", "config.yml", "path to config file")
+	steps := fs.Int("steps", 1, "number of migrations to roll back (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(&config.Options{ConfigFile: *configFile})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	m, closer, err := storage.OpenMigrator(ctx, cfg.Storage)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	switch action {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, v := range applied {
+			fmt.Fprintf(out, "applied %d\n", v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return nil
+	case "down":
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		reverted, err := m.Down(ctx, *steps)
+		for _, v := range reverted {
+			fmt.Fprintf(out, "rolled back %d\n", v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "nothing to roll back")
+		}
+		return nil
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, at := "pending", ""
+			switch {
+			case s.Missing:
+				state, at = "applied (file missing)", s.AppliedAt.Format(time.RFC3339)
+			case s.Applied:
+				state, at = "applied", s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		return tw.Flush()
+	}
+	return nil
+}
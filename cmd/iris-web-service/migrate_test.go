@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateCommand(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	yml := fmt.Sprintf("storage:\n  driver: sqlite\n  sqlite:\n    path: %s\n", filepath.Join(dir, "iris.db"))
+	if err := os.WriteFile(configFile, []byte(yml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"status"}, "pending"},
+		{[]string{"up"}, "applied 1"},
+		{[]string{"up"}, "schema is up to date"},
+		{[]string{"status"}, "drop_greetings_created_at  applied"},
+		{[]string{"down"}, "rolled back 2"},
+		{[]string{"down"}, "rolled back 1"},
+		{[]string{"down"}, "nothing to roll back"},
+	}
+	for _, s := range steps {
+		var out bytes.Buffer
+		if err := runMigrate(append(s.args, "-config", configFile), &out); err != nil {
+			t.Fatalf("migrate %v failed: %v", s.args, err)
+		}
+		if !strings.Contains(out.String(), s.want) {
+			t.Errorf("migrate %v output = %q, want it to contain %q", s.args, out.String(), s.want)
+		}
+	}
+}
+
+func TestMigrateCommandUsage(t *testing.T) {
+	var out bytes.Buffer
+	if err := runMigrate(nil, &out); err == nil {
+		t.Error("expected usage error without action")
+	}
+	if err := runMigrate([]string{"sideways"}, &out); err == nil {
+		t.Error("expected usage error for unknown action")
+	}
+	if err := runMigrate([]string{"up", "-config", "nonexistent.yml"}, &out); err == nil {
+		t.Error("expected error for the memory driver")
+	}
+}
@@ -10,8 +10,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/compress"
 	"github.com/Elenetta17/iris-web-service/internal/config"
 	"github.com/Elenetta17/iris-web-service/internal/httpapi"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/metrics"
+	"github.com/Elenetta17/iris-web-service/internal/monitor"
+	"github.com/Elenetta17/iris-web-service/internal/names"
+	"github.com/Elenetta17/iris-web-service/internal/pagecache"
+	"github.com/Elenetta17/iris-web-service/internal/rbac"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+	"github.com/Elenetta17/iris-web-service/internal/storage"
 )
 
 // Run starts the server with the given configuration
@@ -22,9 +33,126 @@ func Run(cfg *config.Config) error {
 // RunWithSignal starts the server and listens for signals on the provided channel
 // If quit is nil, it creates a default signal channel
 func RunWithSignal(cfg *config.Config, quit chan os.Signal) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /{$}", httpapi.FormPage)
-	mux.HandleFunc("POST /hello", httpapi.HelloHandler)
+	keys, err := auth.NewAPIKeyStore(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("loading api keys: %w", err)
+	}
+	tokens := auth.Tokens{APIKeys: keys}
+	if cfg.Auth.JWT.Enabled() {
+		jwt, err := auth.NewJWTVerifier(cfg.Auth.JWT, nil)
+		if err != nil {
+			return fmt.Errorf("loading jwt keys: %w", err)
+		}
+		tokens.JWT = jwt
+	}
+
+	if cfg.Server.DevMode {
+		if _, err := os.Stat(cfg.Server.TemplateDir); err != nil {
+			return fmt.Errorf("dev mode templates: %w", err)
+		}
+		loader, err := httpapi.NewTemplateLoader(os.DirFS(cfg.Server.TemplateDir), true)
+		if err != nil {
+			return fmt.Errorf("loading templates: %w", err)
+		}
+		httpapi.UseTemplates(loader)
+		log.Printf("Development mode: reloading templates from %s", cfg.Server.TemplateDir)
+	}
+
+	policy, err := rbac.NewPolicy(cfg.RBAC)
+	if err != nil {
+		return fmt.Errorf("loading rbac policy: %w", err)
+	}
+	nameRules, err := names.New(cfg.Names)
+	if err != nil {
+		return fmt.Errorf("loading name rules: %w", err)
+	}
+
+	var auditLog *audit.Logger
+	if cfg.Audit.Enabled() {
+		auditLog, err = audit.Open(cfg.Audit)
+		if err != nil {
+			return fmt.Errorf("opening audit log: %w", err)
+		}
+		defer auditLog.Close()
+	}
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	defer stopAudit()
+	go auditLog.Run(auditCtx)
+
+	predictions, err := monitor.New(cfg.Monitor)
+	if err != nil {
+		return fmt.Errorf("opening prediction monitor: %w", err)
+	}
+	defer predictions.Close()
+	metricsRegistry := metrics.NewRegistry()
+	metricsRegistry.Register(predictions.Families()...)
+	metricsRegistry.Register(httpapi.RenderFailures)
+
+	// Templates reloaded in dev mode would be shadowed by cached pages
+	var pages *pagecache.Cache
+	if !cfg.Server.DevMode {
+		pages = pagecache.New(cfg.PageCache)
+		metricsRegistry.Register(pages.Families()...)
+	}
+
+	store, err := storage.Open(context.Background(), cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+
+	sessions := session.NewManager(cfg.Session)
+	sessionsCtx, stopSessions := context.WithCancel(context.Background())
+	defer stopSessions()
+	go sessions.Run(sessionsCtx)
+	handlers := &httpapi.Handlers{
+		Audit:    auditLog,
+		Store:    store,
+		Authz:    policy,
+		Monitor:  predictions,
+		Names:    nameRules,
+		Sessions: sessions,
+	}
+	models, err := loadModels(cfg.Model)
+	if err != nil {
+		store.Close()
+		return fmt.Errorf("loading models: %w", err)
+	}
+	handlers.Models = models
+
+	// cached serves a page that only varies by language through the page
+	// cache
+	cached := func(pattern string) httpapi.RouteOption {
+		cacheControl, ok := cfg.PageCache.CacheControl[pattern]
+		if !ok {
+			cacheControl = pagecache.DefaultCacheControl
+		}
+		return httpapi.Cached(pages, cacheControl, handlers.PageVariant)
+	}
+
+	mux := httpapi.NewRouter(policy)
+	mux.Audit = auditLog
+	mux.HandleFunc("GET /{$}", handlers.FormPage, cached("GET /{$}"))
+	mux.HandleFunc("POST /hello", handlers.HelloHandler)
+	mux.HandleFunc("GET /hello/{id}", handlers.HelloResult)
+	mux.HandleFunc("GET /greetings", handlers.GreetingsPage)
+	mux.HandleFunc("GET /predict", handlers.PredictPage, cached("GET /predict"))
+	mux.HandleFunc("POST /predict", handlers.PredictPage)
+	mux.HandleFunc("GET /dataset", handlers.DatasetPage, cached("GET /dataset"))
+	mux.HandleFunc("GET /dataset.csv", handlers.DatasetCSV, cached("GET /dataset.csv"))
+	mux.HandleFunc("GET /dataset.json", handlers.DatasetJSON, cached("GET /dataset.json"))
+	mux.HandleFunc("POST /greetings/{id}/delete", handlers.DeleteGreeting,
+		httpapi.RequirePermission(httpapi.PermGreetingsDelete))
+	mux.HandleFunc("GET /admin/drift", handlers.DriftPage,
+		httpapi.RequirePermission(httpapi.PermMonitoringRead))
+
+	if cfg.Auth.OIDC.Enabled() {
+		oidc := auth.NewOIDC(cfg.Auth.OIDC, sessions, nil)
+		oidc.Error = httpapi.Error
+		mux.LoginURL = "/auth/login"
+		mux.HandleFunc("GET /auth/login", oidc.Login)
+		mux.HandleFunc("GET /auth/callback", oidc.Callback)
+		mux.HandleFunc("POST /auth/logout", oidc.Logout)
+	}
 
 	// Add a slow endpoint for testing shutdown behavior
 	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
@@ -33,9 +161,33 @@ func RunWithSignal(cfg *config.Config, quit chan os.Signal) error {
 		w.Write([]byte("done"))
 	})
 
+	// Machine clients authenticate with bearer tokens on the /api tree,
+	// browsers with a session cookie everywhere else
+	api := httpapi.NewRouter(policy)
+	api.Audit = auditLog
+	api.HandleFunc("GET /api/v1/whoami", httpapi.WhoAmI)
+	api.HandleFunc("POST /api/v1/predict", handlers.PredictAPI,
+		httpapi.RequireScope(httpapi.ScopePredict))
+	api.HandleFunc("POST /api/v1/predict/batch", handlers.PredictBatch,
+		httpapi.RequireScope(httpapi.ScopePredict))
+	api.HandleFunc("GET /api/v1/models", handlers.ListModels,
+		httpapi.RequireScope(httpapi.ScopeModelsRead))
+	api.HandleFunc("POST /api/v1/models/{version}/promote", handlers.PromoteModel,
+		httpapi.RequireScope(httpapi.ScopeModelsManage), httpapi.RequirePermission(httpapi.PermModelsManage))
+	api.HandleFunc("PUT /api/v1/models/routing", handlers.SetModelRouting,
+		httpapi.RequireScope(httpapi.ScopeModelsManage), httpapi.RequirePermission(httpapi.PermModelsManage))
+
+	root := http.NewServeMux()
+	// Scrapers are expected to reach /metrics over an internal network, so
+	// it is served without authentication
+	root.Handle("GET /metrics", metricsRegistry)
+	root.Handle("GET "+httpapi.StaticPrefix, httpapi.Static())
+	root.Handle("/api/", auth.Bearer(tokens, httpapi.Error)(api))
+	root.Handle("/", sessions.Middleware(auth.SessionPrincipal(httpapi.Locale(mux))))
+
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      mux,
+		Handler:      httpapi.RequestID(httpapi.Recover(compress.Middleware(compress.Options{})(root))),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
@@ -60,6 +212,7 @@ func RunWithSignal(cfg *config.Config, quit chan os.Signal) error {
 	case <-quit:
 		log.Println("Shutting down server...")
 	case err := <-serverErr:
+		store.Close()
 		return fmt.Errorf("server error: %w", err)
 	}
 
@@ -67,10 +220,47 @@ func RunWithSignal(cfg *config.Config, quit chan os.Signal) error {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server forced to shutdown: %w", err)
+	shutdownErr := server.Shutdown(ctx)
+
+	// Close the storage only once requests have drained, so in-flight
+	// queries are not cut off
+	if err := store.Close(); err != nil {
+		log.Printf("Closing storage: %v", err)
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("server forced to shutdown: %w", shutdownErr)
 	}
 
 	log.Println("Server stopped")
 	return nil
 }
+
+// loadModels builds the model registry described by cfg
+func loadModels(cfg config.ModelConfig) (*iris.Registry, error) {
+	routing := iris.Routing{
+		Primary:         cfg.Primary,
+		Candidate:       cfg.Candidate,
+		CandidateWeight: cfg.CandidateWeight,
+	}
+	var reg *iris.Registry
+	var err error
+	switch {
+	case cfg.Dir != "":
+		reg, err = iris.LoadRegistry(cfg.Dir, routing)
+	case cfg.Path != "":
+		var model *iris.Model
+		model, err = iris.LoadModel(cfg.Path)
+		if err == nil {
+			reg, err = iris.NewRegistry([]iris.Entry{iris.EntryFor(model)}, routing)
+		}
+	default:
+		reg, err = iris.NewRegistry([]iris.Entry{iris.BundledEntry()}, routing)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := reg.Routing()
+	log.Printf("Serving model %s (candidate %q at %.0f%%)", r.Primary, r.Candidate, 100*r.CandidateWeight)
+	return reg, nil
+}
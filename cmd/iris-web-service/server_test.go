@@ -1,14 +1,17 @@
 package main
 
 import (
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings" // Add this import
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
 )
 
 func TestRunFunction(t *testing.T) {
@@ -36,12 +39,34 @@ func TestRunFunction(t *testing.T) {
 	if err != nil {
 		t.Fatalf("server not responding: %v", err)
 	}
+	io.Copy(io.Discard, resp.Body)
 	resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("expected status 200, got %d", resp.StatusCode)
 	}
 
+	// The default transport asks for gzip and decompresses transparently
+	resp, err = http.Get("http://localhost:8887/dataset")
+	if err != nil {
+		t.Fatalf("dataset page not responding: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !resp.Uncompressed {
+		t.Errorf("dataset page: status %d, compressed %v", resp.StatusCode, resp.Uncompressed)
+	}
+
+	resp, err = http.Get("http://localhost:8887/metrics")
+	if err != nil {
+		t.Fatalf("metrics not responding: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("metrics: status %d, Content-Type %q", resp.StatusCode, ct)
+	}
+
 	// Send actual signal to the process to trigger shutdown
 	// (Run creates its own signal channel, so we need to send a real signal)
 	proc, err := os.FindProcess(os.Getpid())
@@ -91,6 +116,7 @@ func TestRunServerStartupError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("first server not responding: %v", err)
 	}
+	io.Copy(io.Discard, resp.Body)
 	resp.Body.Close()
 
 	// Try to start second server on same port (should fail immediately)
@@ -170,3 +196,43 @@ func TestRunShutdownTimeout(t *testing.T) {
 		t.Error("test timeout - server didn't return error")
 	}
 }
+
+func TestRunDevModeMissingTemplates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.DevMode = true
+	cfg.Server.TemplateDir = filepath.Join(t.TempDir(), "missing")
+
+	err := RunWithSignal(cfg, make(chan os.Signal, 1))
+	if err == nil || !strings.Contains(err.Error(), "dev mode templates") {
+		t.Errorf("RunWithSignal() = %v, want dev mode templates error", err)
+	}
+}
+
+func TestLoadModels(t *testing.T) {
+	reg, err := loadModels(config.ModelConfig{})
+	if err != nil {
+		t.Fatalf("loadModels() with defaults failed: %v", err)
+	}
+	if got := reg.Routing().Primary; got != iris.BundledVersion {
+		t.Errorf("default primary = %q, want %q", got, iris.BundledVersion)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.json")
+	if err := runTrain([]string{"-out", path, "-version", "v1", "-folds", "2"}, io.Discard); err != nil {
+		t.Fatalf("train failed: %v", err)
+	}
+	for _, cfg := range []config.ModelConfig{{Path: path}, {Dir: dir}} {
+		reg, err := loadModels(cfg)
+		if err != nil {
+			t.Fatalf("loadModels(%+v) failed: %v", cfg, err)
+		}
+		if got := reg.Routing().Primary; got != "v1" {
+			t.Errorf("loadModels(%+v) primary = %q, want v1", cfg, got)
+		}
+	}
+
+	if _, err := loadModels(config.ModelConfig{Dir: dir, Primary: "v2"}); err == nil {
+		t.Error("expected error for an unknown primary")
+	}
+}
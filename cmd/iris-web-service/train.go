@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+// trainCommand implements "iris-web-service train"
+func trainCommand(args []string) error {
+	return runTrain(args, os.Stdout)
+}
+
+func runTrain(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+	data := fs.String("data", "", "CSV dataset with the four measurements and a species column (defaults to the bundled Iris dataset)")
+	outPath := fs.String("out", "model.json", "where to write the model artifact")
+	folds := fs.Int("folds", 5, "number of cross-validation folds")
+	version := fs.String("version", "", "model version (defaults to the training time)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: iris-web-service train [-data iris.csv] [-out model.json] [-folds n] [-version v]")
+	}
+
+	samples := iris.Dataset()
+	if *data != "" {
+		f, err := os.Open(*data)
+		if err != nil {
+			return err
+		}
+		samples, err = iris.ReadCSV(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", *data, err)
+		}
+	}
+
+	train := func(s []iris.Sample) (iris.Classifier, error) {
+		return iris.TrainLogistic(s, iris.DefaultTrainOptions)
+	}
+	ev, err := iris.CrossValidate(samples, *folds, train)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "cross-validated accuracy: %.3f (%d samples, %d folds)\n\n", ev.Accuracy, len(samples), *folds)
+	if err := writeConfusion(out, ev); err != nil {
+		return err
+	}
+
+	model, err := iris.TrainLogistic(samples, iris.DefaultTrainOptions)
+	if err != nil {
+		return err
+	}
+	model.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	model.Version = *version
+	if model.Version == "" {
+		model.Version = model.CreatedAt.Format("20060102-150405")
+	}
+	model.Accuracy = ev.Accuracy
+	if err := model.Save(*outPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nwrote model %s to %s\n", model.Version, *outPath)
+	return nil
+}
+
+// writeConfusion prints the confusion matrix with actual species as rows
+// and predicted species as columns
+func writeConfusion(out io.Writer, ev iris.Evaluation) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "actual \\ predicted\t")
+	for _, c := range ev.Classes {
+		fmt.Fprintf(tw, "%s\t", c)
+	}
+	fmt.Fprintln(tw)
+	for i, c := range ev.Classes {
+		fmt.Fprintf(tw, "%s\t", c)
+		for _, n := range ev.Confusion[i] {
+			fmt.Fprintf(tw, "%d\t", n)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
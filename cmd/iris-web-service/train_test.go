@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+func TestTrainCommand(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "iris.csv")
+	csv := "sepal_length,sepal_width,petal_length,petal_width,species\n"
+	for _, s := range iris.Dataset() {
+		csv += strings.Join([]string{
+			ftoa(s.SepalLength), ftoa(s.SepalWidth), ftoa(s.PetalLength), ftoa(s.PetalWidth), s.Species,
+		}, ",") + "\n"
+	}
+	if err := os.WriteFile(data, []byte(csv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "model.json")
+	var stdout bytes.Buffer
+	if err := runTrain([]string{"-data", data, "-out", out, "-version", "test-1"}, &stdout); err != nil {
+		t.Fatalf("train failed: %v", err)
+	}
+	for _, want := range []string{"cross-validated accuracy: 0.9", "actual \\ predicted", "virginica", "wrote model test-1"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	m, err := iris.LoadModel(out)
+	if err != nil {
+		t.Fatalf("LoadModel() failed: %v", err)
+	}
+	if m.Version != "test-1" || m.Samples != 150 || m.Accuracy < 0.9 {
+		t.Errorf("unexpected artifact metadata %+v", m)
+	}
+}
+
+func TestTrainCommandErrors(t *testing.T) {
+	var out bytes.Buffer
+	if err := runTrain([]string{"-data", "nonexistent.csv"}, &out); err == nil {
+		t.Error("expected error for missing dataset")
+	}
+	if err := runTrain([]string{"-folds", "1", "-out", filepath.Join(t.TempDir(), "m.json")}, &out); err == nil {
+		t.Error("expected error for a single fold")
+	}
+	if err := runTrain([]string{"extra"}, &out); err == nil {
+		t.Error("expected usage error for positional arguments")
+	}
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,19 @@
+// Command precompress writes .gz and .br variants of the static files in
+// a directory, so that they can be served without compressing per request
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/Elenetta17/iris-web-service/internal/assets"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: precompress <dir>")
+	}
+	if err := assets.Precompress(os.Args[1]); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,297 @@
+// Package assets serves static files under content-hashed URLs so that
+// browsers can cache them for good
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Cache-Control values for fingerprinted URLs, which never change, and
+// plain names, which must be revalidated
+const (
+	CacheImmutable  = "public, max-age=31536000, immutable"
+	CacheRevalidate = "no-cache"
+)
+
+// hashLen is the number of hex digits of the content hash put in URLs
+const hashLen = 10
+
+// encodings of precompressed variants, in order of preference
+var encodings = []struct {
+	name, ext string
+	reader    func(io.Reader) (io.Reader, error)
+}{
+	{"br", ".br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	{"gzip", ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+}
+
+type asset struct {
+	name        string
+	hashed      string
+	hash        string
+	contentType string
+	data        []byte
+	// variants maps a content coding to the precompressed bytes
+	variants map[string][]byte
+}
+
+// Store holds the files of a static tree in memory. It is immutable and
+// safe for concurrent use
+type Store struct {
+	prefix string
+	byName map[string]*asset
+	// byHashed maps fingerprinted names like "app.0123456789.css"
+	byHashed map[string]*asset
+}
+
+// New loads every file in fsys. URLs are prefix followed by the
+// fingerprinted file name, e.g. "/static/app.0123456789.css". Files ending
+// in .gz or .br are served as precompressed variants of the file without
+// the suffix; variants that do not decompress to it are ignored
+func New(fsys fs.FS, prefix string) (*Store, error) {
+	s := &Store{
+		prefix:   strings.TrimSuffix(prefix, "/") + "/",
+		byName:   make(map[string]*asset),
+		byHashed: make(map[string]*asset),
+	}
+	var compressed []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := path.Ext(p); ext == ".gz" || ext == ".br" {
+			compressed = append(compressed, p)
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		a := &asset{
+			name:     p,
+			hash:     hex.EncodeToString(sum[:])[:hashLen],
+			data:     data,
+			variants: make(map[string][]byte),
+		}
+		ext := path.Ext(p)
+		a.hashed = strings.TrimSuffix(p, ext) + "." + a.hash + ext
+		if a.contentType = mime.TypeByExtension(ext); a.contentType == "" {
+			a.contentType = http.DetectContentType(data)
+		}
+		s.byName[p] = a
+		s.byHashed[a.hashed] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading assets: %w", err)
+	}
+
+	for _, p := range compressed {
+		if err := s.addVariant(fsys, p); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) addVariant(fsys fs.FS, p string) error {
+	ext := path.Ext(p)
+	a, ok := s.byName[strings.TrimSuffix(p, ext)]
+	if !ok {
+		return nil
+	}
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return fmt.Errorf("loading assets: %w", err)
+	}
+	for _, enc := range encodings {
+		if enc.ext != ext {
+			continue
+		}
+		r, err := enc.reader(bytes.NewReader(data))
+		if err == nil {
+			var plain []byte
+			plain, err = io.ReadAll(r)
+			if err == nil && !bytes.Equal(plain, a.data) {
+				err = fmt.Errorf("content differs from %s", a.name)
+			}
+		}
+		if err != nil {
+			log.Printf("assets: ignoring stale %s: %v", p, err)
+			return nil
+		}
+		if len(data) < len(a.data) {
+			a.variants[enc.name] = data
+		}
+	}
+	return nil
+}
+
+// URL returns the fingerprinted URL of the named file
+func (s *Store) URL(name string) (string, error) {
+	a, ok := s.byName[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return "", fmt.Errorf("unknown asset %q", name)
+	}
+	return s.prefix + a.hashed, nil
+}
+
+// Has reports whether ServeHTTP has a file for the URL path p
+func (s *Store) Has(p string) bool {
+	_, _, ok := s.lookup(p)
+	return ok
+}
+
+// lookup finds the file for the URL path p by its fingerprinted or plain
+// name, with the Cache-Control it may be served with
+func (s *Store) lookup(p string) (*asset, string, bool) {
+	name := strings.TrimPrefix(p, s.prefix)
+	if a, ok := s.byHashed[name]; ok {
+		return a, CacheImmutable, true
+	}
+	a, ok := s.byName[name]
+	return a, CacheRevalidate, ok
+}
+
+// ServeHTTP serves a file by its fingerprinted or plain name. Only
+// fingerprinted URLs may be cached without revalidation
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a, cacheControl, ok := s.lookup(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, coding := a.data, ""
+	for _, enc := range encodings {
+		if v, ok := a.variants[enc.name]; ok && accepts(r.Header.Get("Accept-Encoding"), enc.name) {
+			body, coding = v, enc.name
+			break
+		}
+	}
+	etag := `"` + a.hash + `"`
+	if coding != "" {
+		etag = `"` + a.hash + "-" + coding + `"`
+	}
+
+	h := w.Header()
+	h.Set("Cache-Control", cacheControl)
+	h.Set("ETag", etag)
+	if len(a.variants) > 0 {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", a.contentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	if coding != "" {
+		h.Set("Content-Encoding", coding)
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// accepts reports whether an Accept-Encoding header allows coding. An
+// explicit entry for the coding overrides "*"
+func accepts(header, coding string) bool {
+	exact, star := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch {
+		case strings.EqualFold(name, coding):
+			exact = q
+		case name == "*":
+			star = q
+		}
+	}
+	if exact >= 0 {
+		return exact > 0
+	}
+	return star > 0
+}
+
+// noneMatch reports whether an If-None-Match header matches etag, using
+// the weak comparison required for GET
+func noneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// minCompressSize is the smallest file worth precompressing
+const minCompressSize = 256
+
+// Precompress writes .gz and .br variants next to every file in dir that
+// is large enough to benefit. It is run by go generate whenever the
+// static files change
+func Precompress(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(p); ext == ".gz" || ext == ".br" {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if len(data) < minCompressSize {
+			return nil
+		}
+
+		var gz bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		var br bytes.Buffer
+		bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+		bw.Write(data)
+		if err := bw.Close(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p+".gz", gz.Bytes(), 0o644); err != nil {
+			return err
+		}
+		return os.WriteFile(p+".br", br.Bytes(), 0o644)
+	})
+}
@@ -0,0 +1,212 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+var css = []byte(strings.Repeat("body { color: #333; }\n", 40))
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+func brotlied(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	bw := brotli.NewWriter(&b)
+	bw.Write(data)
+	bw.Close()
+	return b.Bytes()
+}
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(fstest.MapFS{
+		"app.css":       {Data: css},
+		"app.css.gz":    {Data: gzipped(t, css)},
+		"app.css.br":    {Data: brotlied(t, css)},
+		"img/logo.svg":  {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)},
+		"app.js":        {Data: []byte(strings.Repeat("// app\n", 50))},
+		"app.js.gz":     {Data: gzipped(t, []byte("stale"))},
+		"orphan.txt.gz": {Data: gzipped(t, []byte("no original"))},
+	}, "/static/")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return s
+}
+
+func get(s *Store, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestURL(t *testing.T) {
+	s := newTestStore(t)
+	u, err := s.URL("app.css")
+	if err != nil {
+		t.Fatalf("URL() failed: %v", err)
+	}
+	if !strings.HasPrefix(u, "/static/app.") || !strings.HasSuffix(u, ".css") || len(u) != len("/static/app..css")+hashLen {
+		t.Errorf("URL() = %q, want /static/app.<hash>.css", u)
+	}
+	if u2, _ := s.URL("img/logo.svg"); !strings.HasPrefix(u2, "/static/img/logo.") {
+		t.Errorf("URL() = %q for a nested file", u2)
+	}
+	if _, err := s.URL("missing.css"); err == nil {
+		t.Error("expected error for unknown asset")
+	}
+}
+
+func TestServeFingerprinted(t *testing.T) {
+	s := newTestStore(t)
+	u, _ := s.URL("app.css")
+	rr := get(s, u, nil)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != CacheImmutable {
+		t.Errorf("Cache-Control = %q, want %q", got, CacheImmutable)
+	}
+	if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !bytes.Equal(rr.Body.Bytes(), css) || rr.Header().Get("Content-Encoding") != "" {
+		t.Error("identity body not served")
+	}
+
+	rr = get(s, "/static/app.css", nil)
+	if got := rr.Header().Get("Cache-Control"); rr.Code != http.StatusOK || got != CacheRevalidate {
+		t.Errorf("plain name: status %d, Cache-Control %q", rr.Code, got)
+	}
+
+	for _, target := range []string{"/static/app.0000000000.css", "/static/missing.css", "/static/orphan.txt"} {
+		if rr := get(s, target, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", target, rr.Code)
+		}
+	}
+}
+
+func TestServeNegotiatesEncoding(t *testing.T) {
+	s := newTestStore(t)
+	u, _ := s.URL("app.css")
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		rr := get(s, u, map[string]string{"Accept-Encoding": tt.accept})
+		if got := rr.Header().Get("Content-Encoding"); got != tt.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", tt.accept, got, tt.want)
+		}
+		if rr.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: missing Vary", tt.accept)
+		}
+		if rr.Header().Get("Content-Length") != strconv.Itoa(rr.Body.Len()) {
+			t.Errorf("Accept-Encoding %q: wrong Content-Length", tt.accept)
+		}
+	}
+
+	// The stale gzip variant of app.js is not served
+	rr := get(s, "/static/app.js", map[string]string{"Accept-Encoding": "gzip"})
+	if rr.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(rr.Body.String(), "// app") {
+		t.Error("stale variant served")
+	}
+}
+
+func TestServeNotModified(t *testing.T) {
+	s := newTestStore(t)
+	u, _ := s.URL("app.css")
+	first := get(s, u, map[string]string{"Accept-Encoding": "gzip"})
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	identity := get(s, u, nil).Header().Get("ETag")
+	if identity == etag {
+		t.Error("encoded and identity responses share an ETag")
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rr := get(s, u, map[string]string{"Accept-Encoding": "gzip", "If-None-Match": inm})
+		if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status = %d, want 304 without body", inm, rr.Code)
+		}
+		if rr.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 lacks ETag", inm)
+		}
+	}
+	if rr := get(s, u, map[string]string{"If-None-Match": `"other"`}); rr.Code != http.StatusOK {
+		t.Errorf("mismatched If-None-Match: status = %d, want 200", rr.Code)
+	}
+}
+
+func TestServeHead(t *testing.T) {
+	s := newTestStore(t)
+	req := httptest.NewRequest(http.MethodHead, "/static/app.css", nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 || rr.Header().Get("Content-Length") != strconv.Itoa(len(css)) {
+		t.Errorf("HEAD: status %d, body %d bytes, Content-Length %s", rr.Code, rr.Body.Len(), rr.Header().Get("Content-Length"))
+	}
+}
+
+func TestPrecompress(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.css"), css, 0o644)
+	os.WriteFile(filepath.Join(dir, "tiny.js"), []byte("x()"), 0o644)
+	if err := Precompress(dir); err != nil {
+		t.Fatalf("Precompress() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tiny.js.gz")); err == nil {
+		t.Error("tiny file was compressed")
+	}
+
+	s, err := New(os.DirFS(dir), "/static")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	for _, coding := range []string{"gzip", "br"} {
+		if rr := get(s, "/static/app.css", map[string]string{"Accept-Encoding": coding}); rr.Header().Get("Content-Encoding") != coding {
+			t.Errorf("%s variant not generated", coding)
+		}
+	}
+
+	// Running again is harmless: variants are not compressed themselves
+	if err := Precompress(dir); err != nil {
+		t.Fatalf("second Precompress() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.css.gz.gz")); err == nil {
+		t.Error("variant was compressed again")
+	}
+}
@@ -0,0 +1,381 @@
+// Package audit writes an append-only, hash-chained log of user and admin
+// actions as JSON lines
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// GenesisHash is the prev_hash of the very first record in a chain
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Actions recorded by the service
+const (
+	ActionAuthz           = "authz"
+	ActionGreeting        = "greeting.submitted"
+	ActionGreetingDeleted = "admin.greeting.deleted"
+	ActionModelPromoted   = "admin.model.promoted"
+	ActionModelRouting    = "admin.model.routing_changed"
+)
+
+// syncInterval bounds how long routine events may sit unsynced. Admin
+// events are synced before Record returns
+const syncInterval = time.Second
+
+// durable reports whether an event of action must reach the disk before
+// Record returns. Others, such as the authz event logged for every
+// request, are synced in groups by Run
+func durable(action string) bool {
+	return strings.HasPrefix(action, "admin.")
+}
+
+// Event is a single audit record. Seq, PrevHash and Hash are filled in by
+// the Logger
+type Event struct {
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash,omitempty"`
+}
+
+// computeHash hashes the record with its Hash field cleared. encoding/json
+// emits struct fields in declaration order and map keys sorted, so the
+// encoding is deterministic
+func computeHash(e Event) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Logger appends events to a file, rotating it once it grows past the
+// configured size. The chain continues across rotations and restarts
+type Logger struct {
+	path     string
+	maxBytes int64
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	dirty    bool
+	closed   bool
+	seq      int64
+	lastHash string
+	now      func() time.Time
+}
+
+// Open opens (or creates) the audit log at cfg.Path and resumes the chain
+// from the last record written
+func Open(cfg config.AuditConfig) (*Logger, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("audit log path is empty")
+	}
+	l := &Logger{
+		path:     cfg.Path,
+		maxBytes: cfg.MaxBytes,
+		lastHash: GenesisHash,
+		now:      time.Now,
+	}
+
+	files, err := Files(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		last, ok, err := lastEvent(files[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			l.seq = last.Seq
+			l.lastHash = last.Hash
+			break
+		}
+	}
+
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) openFile() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// Record appends e to the log. A nil Logger discards events, so callers do
+// not need to check whether auditing is enabled
+func (l *Logger) Record(e Event) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errors.New("audit log is closed")
+	}
+	// A failed rotation may have left no file open; retry before giving up
+	// on the event
+	if l.file == nil {
+		if err := l.openFile(); err != nil {
+			return err
+		}
+	}
+
+	e.Seq = l.seq + 1
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	e.Time = e.Time.UTC()
+	e.PrevHash = l.lastHash
+	hash, err := computeHash(e)
+	if err != nil {
+		return fmt.Errorf("hashing audit event: %w", err)
+	}
+	e.Hash = hash
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			if l.file == nil {
+				return err
+			}
+			// Losing events is worse than an oversized file
+			log.Printf("audit: %v", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing audit event: %w", err)
+	}
+	l.seq = e.Seq
+	l.lastHash = e.Hash
+	l.dirty = true
+	if durable(e.Action) {
+		return l.sync()
+	}
+	return nil
+}
+
+// sync flushes the records written since the last sync to disk. Callers
+// must hold l.mu
+func (l *Logger) sync() error {
+	if !l.dirty || l.file == nil {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing audit log: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// Run syncs routine events to disk periodically until ctx is done
+func (l *Logger) Run(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			err := l.sync()
+			l.mu.Unlock()
+			if err != nil {
+				log.Printf("audit: %v", err)
+			}
+		}
+	}
+}
+
+// rotate renames the current file aside and starts a new one. Callers must
+// hold l.mu
+func (l *Logger) rotate() error {
+	if err := l.sync(); err != nil {
+		return err
+	}
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return l.reopen(fmt.Errorf("closing audit log: %w", err))
+	}
+	ext := filepath.Ext(l.path)
+	rotated := fmt.Sprintf("%s-%020d%s", strings.TrimSuffix(l.path, ext), l.seq, ext)
+	if err := os.Rename(l.path, rotated); err != nil {
+		return l.reopen(fmt.Errorf("rotating audit log: %w", err))
+	}
+	return l.openFile()
+}
+
+// reopen appends to the current file again after a failed rotation, so
+// that later events are still recorded, and returns err. Callers must
+// hold l.mu
+func (l *Logger) reopen(err error) error {
+	return errors.Join(err, l.openFile())
+}
+
+// Close flushes and closes the log file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := errors.Join(l.sync(), l.file.Close())
+	l.file = nil
+	return err
+}
+
+// Files returns the rotated files of the log at path, oldest first,
+// followed by path itself if it exists
+func Files(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	pattern := strings.TrimSuffix(path, ext) + "-" + strings.Repeat("[0-9]", 20) + ext
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+func lastEvent(path string) (Event, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Event{}, false, fmt.Errorf("reading audit log: %w", err)
+	}
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return Event{}, false, nil
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Event{}, false, fmt.Errorf("%s: decoding last record: %w", path, err)
+	}
+	return e, true, nil
+}
+
+// VerifyResult summarizes a successful chain verification
+type VerifyResult struct {
+	Files   int
+	Records int64
+}
+
+// Verify checks the hash chain across all files of the log at path. It
+// returns an error identifying the first record that does not match
+func Verify(path string) (VerifyResult, error) {
+	files, err := Files(path)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if len(files) == 0 {
+		return VerifyResult{}, fmt.Errorf("no audit log found at %s", path)
+	}
+
+	res := VerifyResult{Files: len(files)}
+	prev := GenesisHash
+	var seq int64
+	for _, file := range files {
+		if err := verifyFile(file, &prev, &seq); err != nil {
+			return res, err
+		}
+	}
+	res.Records = seq
+	return res, nil
+}
+
+func verifyFile(path string, prev *string, seq *int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("%s:%d: invalid record: %w", path, line, err)
+		}
+		if e.Seq != *seq+1 {
+			return fmt.Errorf("%s:%d: expected seq %d, got %d", path, line, *seq+1, e.Seq)
+		}
+		if e.PrevHash != *prev {
+			return fmt.Errorf("%s:%d: prev_hash does not match previous record", path, line)
+		}
+		want, err := computeHash(e)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if e.Hash != want {
+			return fmt.Errorf("%s:%d: hash mismatch, record was modified", path, line)
+		}
+		*prev = e.Hash
+		*seq = e.Seq
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func openTestLog(t *testing.T, path string, maxBytes int64) *Logger {
+	t.Helper()
+	l, err := Open(config.AuditConfig{Path: path, MaxBytes: maxBytes})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	return l
+}
+
+func recordN(t *testing.T, l *Logger, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := l.Record(Event{
+			Action:  ActionGreeting,
+			Actor:   "ip:192.0.2.1",
+			Details: map[string]string{"name": strings.Repeat("x", i)},
+		})
+		if err != nil {
+			t.Fatalf("Record() failed: %v", err)
+		}
+	}
+}
+
+func TestLoggerChainAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := openTestLog(t, path, 0)
+	recordN(t, l, 3)
+	l.Close()
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if res.Records != 3 || res.Files != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestLoggerResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := openTestLog(t, path, 0)
+	recordN(t, l, 2)
+	l.Close()
+
+	l = openTestLog(t, path, 0)
+	recordN(t, l, 2)
+	l.Close()
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() failed after restart: %v", err)
+	}
+	if res.Records != 4 {
+		t.Errorf("expected 4 records, got %d", res.Records)
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := openTestLog(t, path, 400)
+	recordN(t, l, 10)
+	l.Close()
+
+	files, err := Files(path)
+	if err != nil {
+		t.Fatalf("Files() failed: %v", err)
+	}
+	if len(files) < 3 {
+		t.Fatalf("expected rotation into several files, got %v", files)
+	}
+	if files[len(files)-1] != path {
+		t.Errorf("expected current file last, got %v", files)
+	}
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() failed across rotations: %v", err)
+	}
+	if res.Records != 10 || res.Files != len(files) {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	// Deleting a rotated file breaks the chain
+	if err := os.Remove(files[1]); err != nil {
+		t.Fatalf("removing rotated file: %v", err)
+	}
+	if _, err := Verify(path); err == nil {
+		t.Error("expected Verify() to detect the missing file")
+	}
+}
+
+func TestLoggerSyncsAdminEventsOnly(t *testing.T) {
+	l := openTestLog(t, filepath.Join(t.TempDir(), "audit.log"), 0)
+	defer l.Close()
+
+	tests := []struct {
+		action    string
+		wantDirty bool
+	}{
+		{ActionAuthz, true},
+		{ActionGreeting, true},
+		{ActionModelPromoted, false},
+	}
+	for _, tc := range tests {
+		if err := l.Record(Event{Action: tc.action, Actor: "user:alice"}); err != nil {
+			t.Fatalf("Record(%s) failed: %v", tc.action, err)
+		}
+		if l.dirty != tc.wantDirty {
+			t.Errorf("after %s: dirty = %v, want %v", tc.action, l.dirty, tc.wantDirty)
+		}
+	}
+}
+
+func TestLoggerSurvivesFailedRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	l := openTestLog(t, path, 400)
+	defer l.Close()
+	recordN(t, l, 1)
+
+	// A non-empty directory where the file is rotated to makes the
+	// rename fail
+	blocker := filepath.Join(dir, "audit-00000000000000000001.log")
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0o700); err != nil {
+		t.Fatalf("creating blocker: %v", err)
+	}
+	recordN(t, l, 5)
+
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatalf("removing blocker: %v", err)
+	}
+	recordN(t, l, 5)
+	l.Close()
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if res.Records != 11 {
+		t.Errorf("Records = %d, want 11", res.Records)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := map[string]func(lines []string) []string{
+		"modified": func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], "ip:192.0.2.1", "ip:198.51.100.7", 1)
+			return lines
+		},
+		"deleted": func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		},
+		"reordered": func(lines []string) []string {
+			lines[0], lines[1] = lines[1], lines[0]
+			return lines
+		},
+	}
+
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			l := openTestLog(t, path, 0)
+			recordN(t, l, 3)
+			l.Close()
+
+			data, _ := os.ReadFile(path)
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			lines = tamper(lines)
+			os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+
+			if _, err := Verify(path); err == nil {
+				t.Error("expected Verify() to detect tampering")
+			}
+		})
+	}
+}
+
+func TestNilLoggerDiscards(t *testing.T) {
+	var l *Logger
+	if err := l.Record(Event{Action: ActionGreeting}); err != nil {
+		t.Errorf("nil Logger Record() = %v, want nil", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("nil Logger Close() = %v, want nil", err)
+	}
+}
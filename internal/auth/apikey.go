@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const hashPrefix = "sha256:"
+
+var (
+	// ErrInvalidToken is returned when a bearer token is unknown or malformed
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned when a bearer token is no longer valid
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// TokenVerifier turns a bearer token into a Principal
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*Principal, error)
+}
+
+type apiKey struct {
+	name      string
+	digest    []byte
+	scopes    []string
+	roles     []string
+	expiresAt time.Time
+}
+
+// APIKeyStore verifies API keys against a set of configured hashes
+type APIKeyStore struct {
+	keys []apiKey
+	now  func() time.Time
+}
+
+// HashAPIKey returns the hash of key in the format expected by the config
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// NewAPIKeyStore builds a store from the keys in cfg and, if set, cfg.APIKeysFile
+func NewAPIKeyStore(cfg config.AuthConfig) (*APIKeyStore, error) {
+	entries := append([]config.APIKeyConfig(nil), cfg.APIKeys...)
+
+	if cfg.APIKeysFile != "" {
+		fromFile, err := loadKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fromFile...)
+	}
+
+	store := &APIKeyStore{now: time.Now}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if e.Name == "" {
+			return nil, errors.New("api key without a name")
+		}
+		if seen[e.Name] {
+			return nil, fmt.Errorf("duplicate api key name %q", e.Name)
+		}
+		seen[e.Name] = true
+
+		if !strings.HasPrefix(e.Hash, hashPrefix) {
+			return nil, fmt.Errorf("api key %q: hash must start with %q", e.Name, hashPrefix)
+		}
+		digest, err := hex.DecodeString(strings.TrimPrefix(e.Hash, hashPrefix))
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: invalid sha256 digest", e.Name)
+		}
+
+		store.keys = append(store.keys, apiKey{
+			name:      e.Name,
+			digest:    digest,
+			scopes:    e.Scopes,
+			roles:     e.Roles,
+			expiresAt: e.ExpiresAt,
+		})
+	}
+	return store, nil
+}
+
+func loadKeysFile(path string) ([]config.APIKeyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading api keys file: %w", err)
+	}
+	var file struct {
+		APIKeys []config.APIKeyConfig `yaml:"api_keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing api keys file: %w", err)
+	}
+	return file.APIKeys, nil
+}
+
+// VerifyToken implements TokenVerifier
+func (s *APIKeyStore) VerifyToken(_ context.Context, token string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(token))
+
+	// Compare against every key so the timing does not reveal which one matched
+	var match *apiKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].digest) == 1 {
+			match = &s.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidToken
+	}
+	if !match.expiresAt.IsZero() && !s.now().Before(match.expiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	return &Principal{
+		ID:     match.name,
+		Method: MethodAPIKey,
+		Scopes: match.scopes,
+		Roles:  match.roles,
+	}, nil
+}
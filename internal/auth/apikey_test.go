@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func TestAPIKeyStoreVerifyToken(t *testing.T) {
+	store, err := NewAPIKeyStore(config.AuthConfig{
+		APIKeys: []config.APIKeyConfig{
+			{Name: "reporting", Hash: HashAPIKey("secret-1"), Scopes: []string{"greetings:read"}},
+			{Name: "old", Hash: HashAPIKey("secret-2"), ExpiresAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() failed: %v", err)
+	}
+
+	p, err := store.VerifyToken(context.Background(), "secret-1")
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if p.ID != "reporting" || p.Method != MethodAPIKey {
+		t.Errorf("unexpected principal %+v", p)
+	}
+	if !p.HasScope("greetings:read") {
+		t.Errorf("expected scope greetings:read, got %v", p.Scopes)
+	}
+
+	if _, err := store.VerifyToken(context.Background(), "unknown"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := store.VerifyToken(context.Background(), "secret-2"); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestAPIKeyStoreKeysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yml")
+	content := "api_keys:\n  - name: batch\n    hash: " + HashAPIKey("from-file") + "\n    scopes: [predict]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write keys file: %v", err)
+	}
+
+	store, err := NewAPIKeyStore(config.AuthConfig{APIKeysFile: path})
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() failed: %v", err)
+	}
+	p, err := store.VerifyToken(context.Background(), "from-file")
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if p.ID != "batch" || !p.HasScope("predict") {
+		t.Errorf("unexpected principal %+v", p)
+	}
+}
+
+func TestNewAPIKeyStoreErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{"missing name", config.AuthConfig{APIKeys: []config.APIKeyConfig{{Hash: HashAPIKey("x")}}}},
+		{"bad prefix", config.AuthConfig{APIKeys: []config.APIKeyConfig{{Name: "a", Hash: "md5:abc"}}}},
+		{"bad digest", config.AuthConfig{APIKeys: []config.APIKeyConfig{{Name: "a", Hash: "sha256:zz"}}}},
+		{"duplicate", config.AuthConfig{APIKeys: []config.APIKeyConfig{
+			{Name: "a", Hash: HashAPIKey("x")},
+			{Name: "a", Hash: HashAPIKey("y")},
+		}}},
+		{"missing file", config.AuthConfig{APIKeysFile: "nonexistent.yml"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewAPIKeyStore(tc.cfg); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
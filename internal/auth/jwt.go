@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+)
+
+// MethodJWT marks principals authenticated with a gateway-issued JWT
+const MethodJWT = "jwt"
+
+type staticKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// JWTVerifier validates JWTs against static keys and/or a JWKS endpoint
+type JWTVerifier struct {
+	cfg  config.JWTConfig
+	keys map[string]staticKey
+	jwks *jose.JWKSCache
+	now  func() time.Time
+}
+
+// NewJWTVerifier loads the static keys in cfg and prepares the JWKS cache
+func NewJWTVerifier(cfg config.JWTConfig, client *http.Client) (*JWTVerifier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	v := &JWTVerifier{cfg: cfg, keys: make(map[string]staticKey), now: time.Now}
+
+	for _, k := range cfg.Keys {
+		key, err := loadStaticKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", k.KID, err)
+		}
+		if _, dup := v.keys[k.KID]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.KID)
+		}
+		v.keys[k.KID] = staticKey{alg: k.Alg, key: key}
+	}
+
+	if cfg.JWKSURL != "" {
+		v.jwks = jose.NewJWKSCache(cfg.JWKSURL, cfg.JWKSCacheTTL, client)
+	}
+	return v, nil
+}
+
+func loadStaticKey(k config.JWTKeyConfig) (crypto.PublicKey, error) {
+	switch k.Alg {
+	case jose.HS256:
+		if k.Secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		return []byte(k.Secret), nil
+	case jose.RS256, jose.ES256:
+		if k.PublicKeyFile == "" {
+			return nil, fmt.Errorf("%s requires a public_key_file", k.Alg)
+		}
+		return readPublicKey(k.PublicKeyFile)
+	}
+	return nil, fmt.Errorf("unsupported alg %q", k.Alg)
+}
+
+// readPublicKey accepts a PEM encoded PKIX public key or certificate
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// VerifyToken implements TokenVerifier
+func (v *JWTVerifier) VerifyToken(ctx context.Context, raw string) (*Principal, error) {
+	tok, err := jose.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	key, err := v.key(ctx, tok.Header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := tok.Verify(key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	err = tok.Claims.Validate(jose.Expectations{
+		Issuer:   v.cfg.Issuer,
+		Audience: v.cfg.Audience,
+		Now:      v.now(),
+		Skew:     v.cfg.ClockSkew,
+	})
+	switch {
+	case errors.Is(err, jose.ErrExpired):
+		return nil, ErrExpiredToken
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	id := tok.Claims.String(v.cfg.Claims.Subject)
+	if id == "" {
+		return nil, fmt.Errorf("%w: missing %q claim", ErrInvalidToken, v.cfg.Claims.Subject)
+	}
+	return &Principal{
+		ID:     id,
+		Name:   tok.Claims.String(v.cfg.Claims.Name),
+		Method: MethodJWT,
+		Scopes: tok.Claims.Strings(v.cfg.Claims.Scopes),
+		Roles:  tok.Claims.Strings(v.cfg.Claims.Roles),
+	}, nil
+}
+
+// key selects the verification key for a token. Static keys are pinned to
+// their configured algorithm so that, for example, an RS256 public key can
+// never be used as an HS256 secret
+func (v *JWTVerifier) key(ctx context.Context, h jose.Header) (crypto.PublicKey, error) {
+	if k, ok := v.keys[h.Kid]; ok {
+		if k.alg != h.Alg {
+			return nil, fmt.Errorf("alg %q not allowed for key %q", h.Alg, h.Kid)
+		}
+		return k.key, nil
+	}
+	if h.Alg == jose.HS256 {
+		return nil, fmt.Errorf("no shared secret for key %q", h.Kid)
+	}
+	if v.jwks != nil {
+		return v.jwks.Key(ctx, h.Kid)
+	}
+	return nil, fmt.Errorf("%w: %q", jose.ErrUnknownKey, h.Kid)
+}
+
+// Tokens dispatches bearer tokens by shape: compact JWTs go to JWT and
+// everything else to APIKeys. JWT may be nil when JWTs are not accepted
+type Tokens struct {
+	APIKeys TokenVerifier
+	JWT     TokenVerifier
+}
+
+// VerifyToken implements TokenVerifier
+func (t Tokens) VerifyToken(ctx context.Context, token string) (*Principal, error) {
+	if t.JWT != nil && strings.Count(token, ".") == 2 {
+		return t.JWT.VerifyToken(ctx, token)
+	}
+	return t.APIKeys.VerifyToken(ctx, token)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+	"github.com/Elenetta17/iris-web-service/internal/jose/josetest"
+)
+
+func writePublicKey(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshaling public key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writing public key: %v", err)
+	}
+	return path
+}
+
+func jwtTestConfig() config.JWTConfig {
+	cfg := config.DefaultConfig().Auth.JWT
+	cfg.Issuer = "https://gateway.example"
+	cfg.Audience = "iris-web-service"
+	return cfg
+}
+
+func gatewayClaims(now time.Time, extra map[string]any) map[string]any {
+	c := map[string]any{
+		"iss":   "https://gateway.example",
+		"aud":   "iris-web-service",
+		"sub":   "billing-service",
+		"name":  "Billing",
+		"scope": "greetings:read predict",
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nbf":   now.Add(-time.Minute).Unix(),
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	return c
+}
+
+func TestJWTVerifierStaticKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+
+	cfg := jwtTestConfig()
+	cfg.Keys = []config.JWTKeyConfig{
+		{KID: "hmac", Alg: jose.HS256, Secret: "gateway-secret"},
+		{KID: "rsa", Alg: jose.RS256, PublicKeyFile: writePublicKey(t, &rsaKey.PublicKey)},
+	}
+	v, err := NewJWTVerifier(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() failed: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	v.now = func() time.Time { return now }
+
+	sign := func(alg, kid string, key any, claims map[string]any) string {
+		raw, err := josetest.Sign(jose.Header{Alg: alg, Kid: kid}, claims, key)
+		if err != nil {
+			t.Fatalf("Sign() failed: %v", err)
+		}
+		return raw
+	}
+
+	for name, raw := range map[string]string{
+		"HS256": sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, nil)),
+		"RS256": sign(jose.RS256, "rsa", rsaKey, gatewayClaims(now, nil)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := v.VerifyToken(context.Background(), raw)
+			if err != nil {
+				t.Fatalf("VerifyToken() failed: %v", err)
+			}
+			if p.ID != "billing-service" || p.Name != "Billing" || p.Method != MethodJWT {
+				t.Errorf("unexpected principal %+v", p)
+			}
+			if !p.HasScope("greetings:read") || !p.HasScope("predict") {
+				t.Errorf("unexpected scopes %v", p.Scopes)
+			}
+		})
+	}
+
+	// An attacker signs an HS256 token using the RSA public key as the secret
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	confused := sign(jose.HS256, "rsa", pubDER, gatewayClaims(now, nil))
+
+	rejected := map[string]struct {
+		raw  string
+		want error
+	}{
+		"alg confusion":  {confused, ErrInvalidToken},
+		"wrong secret":   {sign(jose.HS256, "hmac", []byte("guess"), gatewayClaims(now, nil)), ErrInvalidToken},
+		"unknown kid":    {sign(jose.HS256, "other", []byte("gateway-secret"), gatewayClaims(now, nil)), ErrInvalidToken},
+		"wrong issuer":   {sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"iss": "https://evil"})), ErrInvalidToken},
+		"wrong audience": {sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"aud": "other"})), ErrInvalidToken},
+		"expired":        {sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"exp": now.Add(-time.Minute).Unix()})), ErrExpiredToken},
+		"not yet valid":  {sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"nbf": now.Add(time.Minute).Unix()})), ErrInvalidToken},
+		"missing sub":    {sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"sub": nil})), ErrInvalidToken},
+		"malformed":      {"not.a.jwt", ErrInvalidToken},
+	}
+	for name, tc := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.VerifyToken(context.Background(), tc.raw); !errors.Is(err, tc.want) {
+				t.Errorf("VerifyToken() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	// Expiry within the configured clock skew is tolerated
+	withinSkew := sign(jose.HS256, "hmac", []byte("gateway-secret"), gatewayClaims(now, map[string]any{"exp": now.Add(-10 * time.Second).Unix()}))
+	if _, err := v.VerifyToken(context.Background(), withinSkew); err != nil {
+		t.Errorf("expected token within clock skew to verify, got %v", err)
+	}
+}
+
+func TestJWTVerifierJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+	jwk, _ := josetest.NewJWK("gw-2024", &ecKey.PublicKey)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JWKSet{Keys: []jose.JWK{jwk}})
+	}))
+	defer srv.Close()
+
+	cfg := jwtTestConfig()
+	cfg.JWKSURL = srv.URL
+	v, err := NewJWTVerifier(cfg, srv.Client())
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() failed: %v", err)
+	}
+
+	raw, err := josetest.Sign(jose.Header{Alg: jose.ES256, Kid: "gw-2024"}, gatewayClaims(time.Now(), nil), ecKey)
+	if err != nil {
+		t.Fatalf("Sign() failed: %v", err)
+	}
+	p, err := v.VerifyToken(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if p.ID != "billing-service" {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	// HS256 tokens never fall through to the JWKS
+	hs, _ := josetest.Sign(jose.Header{Alg: jose.HS256, Kid: "gw-2024"}, gatewayClaims(time.Now(), nil), []byte("x"))
+	if _, err := v.VerifyToken(context.Background(), hs); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestNewJWTVerifierErrors(t *testing.T) {
+	tests := map[string][]config.JWTKeyConfig{
+		"unsupported alg":  {{KID: "a", Alg: "none"}},
+		"missing secret":   {{KID: "a", Alg: jose.HS256}},
+		"missing key file": {{KID: "a", Alg: jose.RS256}},
+		"unreadable file":  {{KID: "a", Alg: jose.ES256, PublicKeyFile: "nonexistent.pem"}},
+		"duplicate kid": {
+			{KID: "a", Alg: jose.HS256, Secret: "x"},
+			{KID: "a", Alg: jose.HS256, Secret: "y"},
+		},
+	}
+	for name, keys := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := jwtTestConfig()
+			cfg.Keys = keys
+			if _, err := NewJWTVerifier(cfg, nil); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+
+	for _, unset := range []string{"issuer", "audience"} {
+		cfg := jwtTestConfig()
+		cfg.Keys = []config.JWTKeyConfig{{KID: "a", Alg: jose.HS256, Secret: "x"}}
+		if unset == "issuer" {
+			cfg.Issuer = ""
+		} else {
+			cfg.Audience = ""
+		}
+		if _, err := NewJWTVerifier(cfg, nil); err == nil || !strings.Contains(err.Error(), unset) {
+			t.Errorf("missing %s: got %v", unset, err)
+		}
+	}
+}
+
+func TestTokensDispatch(t *testing.T) {
+	cfg := jwtTestConfig()
+	cfg.Keys = []config.JWTKeyConfig{{KID: "hmac", Alg: jose.HS256, Secret: "gateway-secret"}}
+	jwt, err := NewJWTVerifier(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() failed: %v", err)
+	}
+	tokens := Tokens{APIKeys: newTestStore(t), JWT: jwt}
+
+	p, err := tokens.VerifyToken(context.Background(), "good-key")
+	if err != nil || p.Method != MethodAPIKey {
+		t.Errorf("expected API key principal, got %+v, %v", p, err)
+	}
+
+	raw, _ := josetest.Sign(jose.Header{Alg: jose.HS256, Kid: "hmac"}, gatewayClaims(time.Now(), nil), []byte("gateway-secret"))
+	p, err = tokens.VerifyToken(context.Background(), raw)
+	if err != nil || p.Method != MethodJWT {
+		t.Errorf("expected JWT principal, got %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const realm = "iris-web-service"
+
+// ErrorFunc writes an error response with a human readable detail. It
+// lets the service render auth failures like its other errors
+type ErrorFunc func(w http.ResponseWriter, r *http.Request, status int, detail string)
+
+// plainError is the ErrorFunc used when none is given
+func plainError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	if detail == "" {
+		detail = http.StatusText(status)
+	}
+	http.Error(w, detail, status)
+}
+
+// Bearer returns middleware that authenticates requests with an
+// "Authorization: Bearer <token>" header and stores the resulting
+// Principal in the request context. Requests without valid credentials
+// are rejected with 401, written by fail or as plain text if fail is nil
+func Bearer(v TokenVerifier, fail ErrorFunc) func(http.Handler) http.Handler {
+	if fail == nil {
+		fail = plainError
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := bearerToken(r)
+			if errors.Is(err, errNoCredentials) {
+				SetChallenge(w.Header(), "", "", "")
+				fail(w, r, http.StatusUnauthorized, "This resource requires an access token.")
+				return
+			}
+			if err != nil {
+				SetChallenge(w.Header(), "invalid_request", err.Error(), "")
+				fail(w, r, http.StatusBadRequest, "The Authorization header is malformed.")
+				return
+			}
+
+			p, err := v.VerifyToken(r.Context(), token)
+			if err != nil {
+				log.Printf("authentication failed: %s %s: %v", r.Method, r.URL.Path, err)
+				SetChallenge(w.Header(), "invalid_token", describe(err), "")
+				fail(w, r, http.StatusUnauthorized, "The access token is invalid or expired.")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+var errNoCredentials = errors.New("no bearer credentials")
+
+// bearerToken extracts the token from the Authorization header. Requests
+// using another scheme are treated as unauthenticated, while a malformed
+// Bearer header is a bad request per RFC 6750 section 3.1
+func bearerToken(r *http.Request) (string, error) {
+	values := r.Header.Values("Authorization")
+	if len(values) == 0 {
+		return "", errNoCredentials
+	}
+	if len(values) > 1 {
+		return "", errors.New("multiple authorization headers")
+	}
+	scheme, token, _ := strings.Cut(values[0], " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", errNoCredentials
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", errors.New("malformed bearer token")
+	}
+	return token, nil
+}
+
+func describe(err error) string {
+	if errors.Is(err, ErrExpiredToken) {
+		return "the access token expired"
+	}
+	return "the access token is invalid"
+}
+
+// SetChallenge sets an RFC 6750 WWW-Authenticate header. code,
+// description and scope are omitted when empty
+func SetChallenge(h http.Header, code, description, scope string) {
+	params := []string{fmt.Sprintf("realm=%q", realm)}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	if scope != "" {
+		params = append(params, fmt.Sprintf("scope=%q", scope))
+	}
+	h.Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func newTestStore(t *testing.T) *APIKeyStore {
+	t.Helper()
+	store, err := NewAPIKeyStore(config.AuthConfig{
+		APIKeys: []config.APIKeyConfig{
+			{Name: "client", Hash: HashAPIKey("good-key"), Scopes: []string{"greetings:read"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() failed: %v", err)
+	}
+	return store
+}
+
+func TestBearerMiddleware(t *testing.T) {
+	var got *Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	})
+	handler := Bearer(newTestStore(t), nil)(next)
+
+	tests := []struct {
+		name      string
+		header    string
+		status    int
+		challenge string
+	}{
+		{"valid", "Bearer good-key", http.StatusOK, ""},
+		{"lowercase scheme", "bearer good-key", http.StatusOK, ""},
+		{"missing", "", http.StatusUnauthorized, `Bearer realm="iris-web-service"`},
+		{"basic scheme", "Basic Zm9vOmJhcg==", http.StatusUnauthorized, `Bearer realm="iris-web-service"`},
+		{"wrong key", "Bearer bad-key", http.StatusUnauthorized, `error="invalid_token"`},
+		{"empty token", "Bearer ", http.StatusBadRequest, `error="invalid_request"`},
+		{"token with spaces", "Bearer good key", http.StatusBadRequest, `error="invalid_request"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.status)
+			}
+			if tc.status == http.StatusOK {
+				if got == nil || got.ID != "client" {
+					t.Errorf("expected principal client in context, got %+v", got)
+				}
+				return
+			}
+			if h := rr.Header().Get("WWW-Authenticate"); !strings.Contains(h, tc.challenge) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", h, tc.challenge)
+			}
+		})
+	}
+}
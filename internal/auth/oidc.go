@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+)
+
+// MethodOIDC marks principals that signed in through the OIDC provider
+const MethodOIDC = "oidc"
+
+const (
+	sessionPrincipal = "auth.principal"
+	sessionLogin     = "auth.oidc_login"
+
+	// idTokenSkew tolerates small clock differences with the provider
+	idTokenSkew = time.Minute
+)
+
+// providerMetadata is the subset of the OpenID discovery document we use
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// loginState is kept in the session between the redirect to the provider
+// and the callback
+type loginState struct {
+	State    string
+	Nonce    string
+	Verifier string
+	Next     string
+}
+
+// OIDC implements the authorization code flow with PKCE against an
+// OpenID Connect provider and signs the browser in with a session
+type OIDC struct {
+	// Error writes the responses of failed logins, as plain text if nil
+	Error ErrorFunc
+
+	cfg      config.OIDCConfig
+	sessions *session.Manager
+	client   *http.Client
+	now      func() time.Time
+
+	mu       sync.Mutex
+	provider *providerMetadata
+	jwks     *jose.JWKSCache
+	inflight *discovery
+}
+
+// discovery is a provider discovery shared by the requests waiting on it
+type discovery struct {
+	done chan struct{}
+	err  error
+}
+
+// discoveryTimeout bounds a discovery fetch, which is shared by the
+// requests waiting on it and so does not use their contexts
+const discoveryTimeout = 10 * time.Second
+
+// NewOIDC returns an OIDC login flow. Provider discovery happens lazily on
+// the first login so that the service can start while the provider is down
+func NewOIDC(cfg config.OIDCConfig, sessions *session.Manager, client *http.Client) *OIDC {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDC{cfg: cfg, sessions: sessions, client: client, now: time.Now}
+}
+
+// discover returns the provider metadata, fetching it on first use. The
+// lock is not held during the fetch, and concurrent callers share it
+func (o *OIDC) discover(ctx context.Context) (*providerMetadata, *jose.JWKSCache, error) {
+	o.mu.Lock()
+	if o.provider != nil {
+		defer o.mu.Unlock()
+		return o.provider, o.jwks, nil
+	}
+	d := o.inflight
+	if d == nil {
+		d = &discovery{done: make(chan struct{})}
+		o.inflight = d
+		go o.runDiscovery(context.WithoutCancel(ctx), d)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.provider, o.jwks, nil
+}
+
+// runDiscovery performs the discovery d and stores its result
+func (o *OIDC) runDiscovery(ctx context.Context, d *discovery) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	meta, err := o.fetchMetadata(ctx)
+
+	o.mu.Lock()
+	if err == nil {
+		o.provider = meta
+		o.jwks = jose.NewJWKSCache(meta.JWKSURI, o.cfg.JWKSCacheTTL, o.client)
+	}
+	o.inflight = nil
+	o.mu.Unlock()
+
+	d.err = err
+	close(d.done)
+}
+
+func (o *OIDC) fetchMetadata(ctx context.Context) (*providerMetadata, error) {
+	issuer := strings.TrimSuffix(o.cfg.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching discovery document: unexpected status %d", resp.StatusCode)
+	}
+
+	var meta providerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("decoding discovery document: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", meta.Issuer, o.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing required endpoints")
+	}
+	return &meta, nil
+}
+
+func (o *OIDC) fail(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	if o.Error == nil {
+		plainError(w, r, status, detail)
+		return
+	}
+	o.Error(w, r, status, detail)
+}
+
+// Login redirects the browser to the provider's authorization endpoint
+func (o *OIDC) Login(w http.ResponseWriter, r *http.Request) {
+	meta, _, err := o.discover(r.Context())
+	if err != nil {
+		log.Printf("oidc login: %v", err)
+		o.fail(w, r, http.StatusBadGateway, "Login is unavailable right now.")
+		return
+	}
+
+	s := session.FromContext(r.Context())
+	login := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		Next:     safeRedirect(r.URL.Query().Get("next")),
+	}
+	s.Set(sessionLogin, login)
+	o.sessions.Save(w, s)
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.cfg.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"scope":                 {strings.Join(o.cfg.Scopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, meta.AuthorizationEndpoint+"?"+q.Encode(), http.StatusFound)
+}
+
+// Callback completes the login: it exchanges the authorization code,
+// verifies the ID token and stores the resulting Principal in the session
+func (o *OIDC) Callback(w http.ResponseWriter, r *http.Request) {
+	s := session.FromContext(r.Context())
+	login, ok := s.Get(sessionLogin).(loginState)
+	if !ok {
+		o.fail(w, r, http.StatusBadRequest, "No login is in progress.")
+		return
+	}
+	s.Delete(sessionLogin)
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		log.Printf("oidc callback: provider returned error %q: %s", e, q.Get("error_description"))
+		o.fail(w, r, http.StatusUnauthorized, "Login failed.")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(login.State)) != 1 {
+		o.fail(w, r, http.StatusBadRequest, "The login state is invalid.")
+		return
+	}
+
+	p, err := o.exchange(r.Context(), q.Get("code"), login)
+	if err != nil {
+		log.Printf("oidc callback: %v", err)
+		o.fail(w, r, http.StatusUnauthorized, "Login failed.")
+		return
+	}
+
+	s = o.sessions.Renew(w, s)
+	s.Set(sessionPrincipal, p)
+	s.Authenticate()
+	o.sessions.Save(w, s)
+	log.Printf("oidc login: %s signed in", p.ID)
+
+	http.Redirect(w, r, login.Next, http.StatusFound)
+}
+
+// Logout ends the browser session
+func (o *OIDC) Logout(w http.ResponseWriter, r *http.Request) {
+	if s := session.FromContext(r.Context()); s != nil {
+		o.sessions.Destroy(w, s)
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (o *OIDC) exchange(ctx context.Context, code string, login loginState) (*Principal, error) {
+	meta, jwks, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"code_verifier": {login.Verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request: unexpected status %d", resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return o.verifyIDToken(ctx, meta, jwks, tokens.IDToken, login.Nonce)
+}
+
+func (o *OIDC) verifyIDToken(ctx context.Context, meta *providerMetadata, jwks *jose.JWKSCache, raw, nonce string) (*Principal, error) {
+	tok, err := jose.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+	key, err := jwks.Key(ctx, tok.Header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+	if err := tok.Verify(key); err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+	err = tok.Claims.Validate(jose.Expectations{
+		Issuer:   meta.Issuer,
+		Audience: o.cfg.ClientID,
+		Now:      o.now(),
+		Skew:     idTokenSkew,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(tok.Claims.String("nonce")), []byte(nonce)) != 1 {
+		return nil, errors.New("id token: nonce mismatch")
+	}
+
+	sub := tok.Claims.String("sub")
+	if sub == "" {
+		return nil, errors.New("id token: missing sub")
+	}
+
+	name := tok.Claims.String("name")
+	if name == "" {
+		name = tok.Claims.String("preferred_username")
+	}
+	if name == "" {
+		name = tok.Claims.String("email")
+	}
+	return &Principal{
+		ID:     sub,
+		Name:   name,
+		Method: MethodOIDC,
+		Roles:  tok.Claims.Strings(o.cfg.RolesClaim),
+	}, nil
+}
+
+// SessionPrincipal copies the principal of a signed-in browser session into
+// the request context. It must run inside session.Manager.Middleware
+func SessionPrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s := session.FromContext(r.Context()); s != nil {
+			if p, ok := s.Get(sessionPrincipal).(*Principal); ok {
+				r = r.WithContext(WithPrincipal(r.Context(), p))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// safeRedirect only allows local absolute paths so the login flow cannot be
+// used as an open redirect
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("auth: reading random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+	"github.com/Elenetta17/iris-web-service/internal/jose/josetest"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+)
+
+// stubProvider is a minimal OpenID provider: it publishes discovery and
+// JWKS documents and issues ID tokens for codes handed out by authorize
+type stubProvider struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]authRequest
+	audience string // overrides the aud claim when set
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	p := &stubProvider{t: t, key: key, codes: make(map[string]authRequest)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.srv.URL,
+			"authorization_endpoint": p.srv.URL + "/authorize",
+			"token_endpoint":         p.srv.URL + "/token",
+			"jwks_uri":               p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := josetest.NewJWK("stub-key", &p.key.PublicKey)
+		json.NewEncoder(w).Encode(jose.JWKSet{Keys: []jose.JWK{jwk}})
+	})
+	mux.HandleFunc("POST /token", p.token)
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+// authorize simulates the user approving the login at the provider and
+// returns the code the provider would pass to the redirect URI
+func (p *stubProvider) authorize(q url.Values) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := "code-" + q.Get("state")[:8]
+	p.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return code
+}
+
+func (p *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != "iris" || secret != "s3cret" {
+		http.Error(w, "invalid_client", http.StatusUnauthorized)
+		return
+	}
+	r.ParseForm()
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	aud := "iris"
+	if p.audience != "" {
+		aud = p.audience
+	}
+	idToken, err := josetest.Sign(jose.Header{Alg: jose.RS256, Kid: "stub-key"}, map[string]any{
+		"iss":   p.srv.URL,
+		"sub":   "user-42",
+		"aud":   aud,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": req.nonce,
+		"name":  "Alice Example",
+	}, p.key)
+	if err != nil {
+		p.t.Errorf("signing id token: %v", err)
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// browser keeps cookies between requests against an in-process handler
+type browser struct {
+	t       *testing.T
+	handler http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func (b *browser) do(method, target string) *httptest.ResponseRecorder {
+	b.t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	rr := httptest.NewRecorder()
+	b.handler.ServeHTTP(rr, req)
+	for _, c := range rr.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(b.cookies, c.Name)
+		} else {
+			b.cookies[c.Name] = c
+		}
+	}
+	return rr
+}
+
+func newOIDCBrowser(t *testing.T, provider *stubProvider) *browser {
+	t.Helper()
+	sessions := session.NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour})
+	oidc := NewOIDC(config.OIDCConfig{
+		Issuer:       provider.srv.URL,
+		ClientID:     "iris",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://iris.test/auth/callback",
+		Scopes:       []string{"openid", "profile"},
+		JWKSCacheTTL: time.Hour,
+	}, sessions, provider.srv.Client())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /auth/login", oidc.Login)
+	mux.HandleFunc("GET /auth/callback", oidc.Callback)
+	mux.HandleFunc("POST /auth/logout", oidc.Logout)
+	mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		if p := FromContext(r.Context()); p != nil {
+			w.Write([]byte(p.ID + " " + p.Name))
+		}
+	})
+
+	return &browser{
+		t:       t,
+		handler: sessions.Middleware(SessionPrincipal(mux)),
+		cookies: make(map[string]*http.Cookie),
+	}
+}
+
+func startLogin(t *testing.T, b *browser, target string) url.Values {
+	t.Helper()
+	rr := b.do(http.MethodGet, target)
+	if rr.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d: %s", rr.Code, http.StatusFound, rr.Body.String())
+	}
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	return loc.Query()
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	provider := newStubProvider(t)
+	b := newOIDCBrowser(t, provider)
+
+	q := startLogin(t, b, "/auth/login?next=/greetings")
+	for _, param := range []string{"state", "nonce", "code_challenge"} {
+		if q.Get(param) == "" {
+			t.Errorf("authorization request missing %s", param)
+		}
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "iris" || q.Get("scope") != "openid profile" {
+		t.Errorf("unexpected authorization request %v", q)
+	}
+
+	code := provider.authorize(q)
+	rr := b.do(http.MethodGet, "/auth/callback?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode())
+	if rr.Code != http.StatusFound {
+		t.Fatalf("callback status = %d, want %d: %s", rr.Code, http.StatusFound, rr.Body.String())
+	}
+	if loc := rr.Header().Get("Location"); loc != "/greetings" {
+		t.Errorf("callback redirect = %q, want /greetings", loc)
+	}
+
+	if body := b.do(http.MethodGet, "/me").Body.String(); body != "user-42 Alice Example" {
+		t.Errorf("expected signed-in principal, got %q", body)
+	}
+
+	b.do(http.MethodPost, "/auth/logout")
+	if body := b.do(http.MethodGet, "/me").Body.String(); body != "" {
+		t.Errorf("expected anonymous after logout, got %q", body)
+	}
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	provider := newStubProvider(t)
+	b := newOIDCBrowser(t, provider)
+
+	q := startLogin(t, b, "/auth/login")
+	code := provider.authorize(q)
+	rr := b.do(http.MethodGet, "/auth/callback?"+url.Values{"code": {code}, "state": {"forged"}}.Encode())
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if body := b.do(http.MethodGet, "/me").Body.String(); body != "" {
+		t.Errorf("expected anonymous, got %q", body)
+	}
+}
+
+func TestOIDCCallbackRejectsWrongAudience(t *testing.T) {
+	provider := newStubProvider(t)
+	provider.audience = "someone-else"
+	b := newOIDCBrowser(t, provider)
+
+	q := startLogin(t, b, "/auth/login")
+	code := provider.authorize(q)
+	rr := b.do(http.MethodGet, "/auth/callback?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode())
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCCallbackWithoutLogin(t *testing.T) {
+	b := newOIDCBrowser(t, newStubProvider(t))
+	rr := b.do(http.MethodGet, "/auth/callback?code=x&state=y")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"":                  "/",
+		"/greetings":        "/greetings",
+		"//evil.example":    "/",
+		"/\\evil.example":   "/",
+		"https://evil.test": "/",
+	}
+	for in, want := range tests {
+		if got := safeRedirect(in); got != want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOIDCDiscoverDoesNotBlockOnSlowIssuer(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	}))
+	defer srv.Close()
+	oidc := NewOIDC(config.OIDCConfig{Issuer: srv.URL}, nil, srv.Client())
+
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := oidc.discover(context.Background())
+		first <- err
+	}()
+
+	// A login with its own deadline gives up instead of queueing behind
+	// the stalled discovery
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := oidc.discover(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("discover() = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("discover() failed: %v", err)
+	}
+	if meta, _, err := oidc.discover(context.Background()); err != nil || meta.TokenEndpoint != srv.URL+"/token" {
+		t.Errorf("discover() = %+v, %v", meta, err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected 1 discovery fetch, got %d", got)
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import "context"
+
+// Authentication methods recorded on a Principal
+const (
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// ID identifies the caller, e.g. the API key name
+	ID string `json:"id"`
+	// Name is a human readable label, when the identity provider supplies one
+	Name   string   `json:"name,omitempty"`
+	Method string   `json:"method"`
+	Scopes []string `json:"scopes,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or nil if the request is anonymous
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
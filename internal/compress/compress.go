@@ -0,0 +1,316 @@
+// Package compress compresses HTTP responses with brotli or gzip,
+// whichever the client prefers
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultMinSize is the smallest body worth compressing, in bytes
+const DefaultMinSize = 1024
+
+// DefaultTypes are the media types compressed by default. Images other
+// than SVG, archives and the like are compressed already
+var DefaultTypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"image/svg+xml",
+	"text/css",
+	"text/csv",
+	"text/html",
+	"text/javascript",
+	"text/plain",
+}
+
+// brotliLevel trades ratio for speed, since responses are compressed on
+// every request
+const brotliLevel = 5
+
+// encoder is implemented by *gzip.Writer and *brotli.Writer
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// codings are the supported content codings, in order of preference
+var codings = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, brotliLevel) }}},
+	{"gzip", &sync.Pool{New: func() any { return gzip.NewWriter(nil) }}},
+}
+
+// Options configures Middleware. Zero values select the defaults
+type Options struct {
+	// MinSize is the smallest body compressed; DefaultMinSize if zero.
+	// Streamed responses are compressed from their first flush regardless
+	MinSize int
+	// Types lists the compressed media types; DefaultTypes if empty
+	Types []string
+}
+
+// Middleware compresses responses whose media type is in opts.Types,
+// using the coding the client prefers in Accept-Encoding. Responses that
+// already have a Content-Encoding, such as precompressed static assets,
+// are passed through untouched
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.MinSize == 0 {
+		opts.MinSize = DefaultMinSize
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = DefaultTypes
+	}
+	types := make(map[string]bool, len(opts.Types))
+	for _, t := range opts.Types {
+		types[strings.ToLower(t)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &responseWriter{
+				ResponseWriter: w,
+				minSize:        opts.MinSize,
+				types:          types,
+			}
+			if r.Method != http.MethodHead {
+				cw.coding = Negotiate(r.Header.Get("Accept-Encoding"))
+			}
+			// Not deferred: after a panic the buffered body must not be
+			// sent, so that the error page can be written instead
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// Negotiate returns the supported coding the client prefers according to
+// an Accept-Encoding header, or "" when it accepts none
+func Negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	best, bestQ := "", 0.0
+	for _, c := range codings {
+		if q := quality(acceptEncoding, c.name); q > bestQ {
+			best, bestQ = c.name, q
+		}
+	}
+	return best
+}
+
+// quality returns the q-value of coding in an Accept-Encoding header. An
+// explicit entry for the coding overrides "*"
+func quality(header, coding string) float64 {
+	exact, star := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		switch {
+		case strings.EqualFold(name, coding):
+			exact = q
+		case name == "*":
+			star = q
+		}
+	}
+	if exact >= 0 {
+		return exact
+	}
+	return max(star, 0)
+}
+
+// responseWriter buffers the start of the body until it knows whether the
+// response is large enough to compress
+type responseWriter struct {
+	http.ResponseWriter
+	minSize int
+	types   map[string]bool
+	// coding is the negotiated content coding, "" for none
+	coding string
+
+	status int
+	// decided is set once the headers have been sent
+	decided bool
+	buf     []byte
+	enc     encoder
+	pool    *sync.Pool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		// Informational responses such as 103 Early Hints go out as is
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	if !w.mayCompress() {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		w.decide(true)
+		return len(b), w.flushBuffer()
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far, compressing it if the
+// response qualifies, so that streamed responses reach the client
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+		if err := w.flushBuffer(); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// mayCompress reports whether the response qualifies for compression
+// before its body has been seen
+func (w *responseWriter) mayCompress() bool {
+	h := w.Header()
+	switch {
+	case w.coding == "",
+		w.status == http.StatusNoContent,
+		w.status == http.StatusNotModified,
+		w.status == http.StatusPartialContent,
+		h.Get("Content-Encoding") != "",
+		h.Get("Content-Range") != "":
+		return false
+	}
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < w.minSize {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	return ct == "" || w.compressible(ct)
+}
+
+func (w *responseWriter) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && w.types[mediaType]
+}
+
+// decide sends the headers, switching to compression if want is set and
+// the response qualifies
+func (w *responseWriter) decide(want bool) {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff like net/http would, so the type can be checked
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	ct := h.Get("Content-Type")
+	if h.Get("Content-Encoding") == "" && (ct == "" || w.compressible(ct)) {
+		addVary(h, "Accept-Encoding")
+	}
+
+	if want && w.mayCompress() && w.compressible(ct) {
+		for _, c := range codings {
+			if c.name == w.coding {
+				w.pool = c.pool
+			}
+		}
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+		h.Set("Content-Encoding", w.coding)
+		h.Del("Content-Length")
+		// The compressed body is a different representation
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// addVary adds field to the Vary header unless it is listed already
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f == "*" || strings.EqualFold(f, field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+func (w *responseWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// close sends a body too small to compress and finishes the compressed
+// stream
+func (w *responseWriter) close() {
+	if w.status == 0 {
+		// Nothing was written; let net/http send its default response
+		return
+	}
+	if !w.decided {
+		w.decide(false)
+	}
+	w.flushBuffer()
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
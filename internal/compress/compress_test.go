@@ -0,0 +1,231 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+var page = strings.Repeat("<p>Iris setosa, versicolor and virginica.</p>\n", 100)
+
+func serve(h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	Middleware(Options{})(h).ServeHTTP(rr, req)
+	return rr
+}
+
+func decode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader = rr.Body
+	switch coding := rr.Header().Get("Content-Encoding"); coding {
+	case "br":
+		r = brotli.NewReader(r)
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		r = zr
+	case "":
+	default:
+		t.Fatalf("unexpected Content-Encoding %q", coding)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	return string(b)
+}
+
+func respond(contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, body)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		acceptEncoding string
+		coding         string
+		vary           bool
+	}{
+		{"brotli preferred", respond("text/html; charset=utf-8", page), "gzip, deflate, br", "br", true},
+		{"gzip", respond("text/html; charset=utf-8", page), "gzip", "gzip", true},
+		{"client preference", respond("application/json", page), "br;q=0.5, gzip", "gzip", true},
+		{"wildcard", respond("text/css", page), "*", "br", true},
+		{"refused", respond("text/html", page), "br;q=0, gzip;q=0", "", true},
+		{"no accept-encoding", respond("text/html", page), "", "", true},
+		{"too small", respond("text/html", "<p>hi</p>"), "gzip", "", true},
+		{"not allowlisted", respond("image/png", page), "gzip", "", false},
+		{"sniffed type", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "<!DOCTYPE html>"+page)
+		}), "gzip", "gzip", true},
+		{"already encoded", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/css")
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, page)
+		}), "gzip, br", "br", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(tc.handler, tc.acceptEncoding)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tc.coding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tc.coding)
+			}
+			if got := rr.Header().Get("Vary") == "Accept-Encoding"; got != tc.vary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %v", rr.Header().Get("Vary"), tc.vary)
+			}
+			if tc.name == "already encoded" {
+				if rr.Body.String() != page {
+					t.Error("encoded body was changed")
+				}
+				return
+			}
+			body := decode(t, rr)
+			if !strings.HasSuffix(body, page) && body != "<p>hi</p>" {
+				t.Errorf("body does not round-trip: %q", body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	rr := serve(respond("text/html", page), "gzip")
+
+	if got := rr.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want none for a compressed body", got)
+	}
+	if got, want := rr.Header().Get("ETag"), `W/"v1"`; got != want {
+		t.Errorf("ETag = %q, want %q", got, want)
+	}
+	if rr.Body.Len() >= len(page) {
+		t.Errorf("compressed body is %d bytes, page is %d", rr.Body.Len(), len(page))
+	}
+
+	rr = serve(respond("text/html", page), "")
+	if got, want := rr.Header().Get("Content-Length"), strconv.Itoa(len(page)); got != want {
+		t.Errorf("uncompressed Content-Length = %q, want %q", got, want)
+	}
+	if got, want := rr.Header().Get("ETag"), `"v1"`; got != want {
+		t.Errorf("uncompressed ETag = %q, want %q", got, want)
+	}
+}
+
+func TestMiddlewareSkipsBodilessStatuses(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		rr := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(status)
+		}), "gzip")
+		if rr.Code != status {
+			t.Errorf("status = %d, want %d", rr.Code, status)
+		}
+		if got := rr.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%d: Content-Encoding = %q, want none", status, got)
+		}
+		if rr.Body.Len() != 0 {
+			t.Errorf("%d: body = %q, want empty", status, rr.Body.String())
+		}
+	}
+}
+
+func TestMiddlewareKeepsStatus(t *testing.T) {
+	rr := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, page)
+	}), "gzip")
+
+	if got, want := rr.Code, http.StatusNotFound; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if got := decode(t, rr); got != page {
+		t.Error("body does not round-trip")
+	}
+}
+
+func TestMiddlewareStreaming(t *testing.T) {
+	flushed := make(chan string, 1)
+	next := make(chan struct{})
+	srv := httptest.NewServer(Middleware(Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, `{"row":1}`+"\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() failed: %v", err)
+		}
+		<-next
+		io.WriteString(w, `{"row":2}`+"\n")
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	go func() {
+		b := make([]byte, 64)
+		n, _ := zr.Read(b)
+		flushed <- string(b[:n])
+	}()
+	// The first row must arrive before the handler finishes
+	if got := <-flushed; got != `{"row":1}`+"\n" {
+		t.Errorf("first flushed row = %q", got)
+	}
+	close(next)
+	rest, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading stream: %v", err)
+	}
+	if got := string(rest); got != `{"row":2}`+"\n" {
+		t.Errorf("rest of stream = %q", got)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.1, gzip;q=0.9", "gzip"},
+		{"*;q=0.5", "br"},
+		{"*, br;q=0", "gzip"},
+		{"GZIP", "gzip"},
+	}
+	for _, tc := range tests {
+		if got := Negotiate(tc.header); got != tc.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -10,7 +11,16 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig `yaml:"server"`
+	Server    ServerConfig    `yaml:"server"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Session   SessionConfig   `yaml:"session"`
+	RBAC      RBACConfig      `yaml:"rbac"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Model     ModelConfig     `yaml:"model"`
+	Monitor   MonitorConfig   `yaml:"monitoring"`
+	Names     NameConfig      `yaml:"names"`
+	PageCache PageCacheConfig `yaml:"page_cache"`
 }
 
 type ServerConfig struct {
@@ -19,6 +29,199 @@ type ServerConfig struct {
 	WriteTimeout    time.Duration `yaml:"write_timeout"`
 	IdleTimeout     time.Duration `yaml:"idle_timeout"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DevMode reads the page templates from TemplateDir and reloads them
+	// when they change, instead of using the copies built into the binary
+	DevMode     bool   `yaml:"dev_mode"`
+	TemplateDir string `yaml:"template_dir"`
+}
+
+type AuthConfig struct {
+	// APIKeys are accepted as bearer tokens on the /api routes
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	// APIKeysFile optionally points to a YAML file with additional api_keys
+	APIKeysFile string `yaml:"api_keys_file"`
+	// OIDC enables single sign-on for the browser UI when Issuer is set
+	OIDC OIDCConfig `yaml:"oidc"`
+	// JWT accepts gateway-issued tokens on the /api routes
+	JWT JWTConfig `yaml:"jwt"`
+}
+
+type OIDCConfig struct {
+	Issuer       string        `yaml:"issuer"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret string        `yaml:"client_secret"`
+	RedirectURL  string        `yaml:"redirect_url"`
+	Scopes       []string      `yaml:"scopes"`
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl"`
+	// RolesClaim names the ID token claim listing the user's roles
+	RolesClaim string `yaml:"roles_claim"`
+}
+
+// Enabled reports whether an OIDC provider is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// APIKeyConfig describes a single API key. Only the hash of the key is
+// stored, in the form "sha256:<hex digest>"
+type APIKeyConfig struct {
+	Name      string    `yaml:"name"`
+	Hash      string    `yaml:"hash"`
+	Scopes    []string  `yaml:"scopes"`
+	Roles     []string  `yaml:"roles"`
+	ExpiresAt time.Time `yaml:"expires_at"`
+}
+
+type JWTConfig struct {
+	Issuer       string          `yaml:"issuer"`
+	Audience     string          `yaml:"audience"`
+	ClockSkew    time.Duration   `yaml:"clock_skew"`
+	JWKSURL      string          `yaml:"jwks_url"`
+	JWKSCacheTTL time.Duration   `yaml:"jwks_cache_ttl"`
+	Keys         []JWTKeyConfig  `yaml:"keys"`
+	Claims       JWTClaimsConfig `yaml:"claims"`
+}
+
+// Enabled reports whether any verification key source is configured
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSURL != "" || len(c.Keys) > 0
+}
+
+// Validate requires the expected issuer and audience whenever JWT
+// authentication is enabled
+func (c JWTConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Issuer == "" {
+		return errors.New("auth.jwt.issuer is required when jwt keys are configured")
+	}
+	if c.Audience == "" {
+		return errors.New("auth.jwt.audience is required when jwt keys are configured")
+	}
+	return nil
+}
+
+// JWTKeyConfig is a static verification key. HS256 keys use Secret,
+// RS256 and ES256 keys a PEM encoded PublicKeyFile
+type JWTKeyConfig struct {
+	KID           string `yaml:"kid"`
+	Alg           string `yaml:"alg"`
+	Secret        string `yaml:"secret"`
+	PublicKeyFile string `yaml:"public_key_file"`
+}
+
+// JWTClaimsConfig names the claims mapped onto the request principal
+type JWTClaimsConfig struct {
+	Subject string `yaml:"subject"`
+	Name    string `yaml:"name"`
+	Scopes  string `yaml:"scopes"`
+	Roles   string `yaml:"roles"`
+}
+
+type SessionConfig struct {
+	CookieName string        `yaml:"cookie_name"`
+	TTL        time.Duration `yaml:"ttl"`
+	Secure     bool          `yaml:"secure"`
+	// MaxSessions bounds the sessions kept in memory; the oldest is evicted
+	// to make room for a new one
+	MaxSessions int `yaml:"max_sessions"`
+}
+
+// RBACConfig declares which permissions each role grants and which roles
+// are assigned to principals that do not carry roles themselves
+type RBACConfig struct {
+	Roles map[string][]string `yaml:"roles"`
+	// Assignments maps "method:id" principals, e.g. "oidc:alice", to roles
+	Assignments map[string][]string `yaml:"assignments"`
+}
+
+// AuditConfig controls the tamper-evident audit log. Auditing is disabled
+// when Path is empty
+type AuditConfig struct {
+	Path string `yaml:"path"`
+	// MaxBytes is the size at which the log file is rotated
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// Enabled reports whether audit records are written
+func (c AuditConfig) Enabled() bool {
+	return c.Path != ""
+}
+
+// StorageConfig selects where greetings are persisted
+type StorageConfig struct {
+	// Driver is "memory", "sqlite" or "postgres"
+	Driver string `yaml:"driver"`
+	// AutoMigrate applies pending schema migrations when serve starts
+	AutoMigrate bool           `yaml:"auto_migrate"`
+	SQLite      SQLiteConfig   `yaml:"sqlite"`
+	Postgres    PostgresConfig `yaml:"postgres"`
+}
+
+type SQLiteConfig struct {
+	Path string `yaml:"path"`
+}
+
+// PostgresConfig configures the PostgreSQL connection pool
+type PostgresConfig struct {
+	// DSN is a libpq style connection string or postgres:// URL
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// QueryTimeout bounds each query on top of the caller's context, so a
+	// slow database cannot hold a request past it
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+}
+
+// ModelConfig selects the classifiers served by the predict endpoints
+type ModelConfig struct {
+	// Path is a model artifact written by the train command. The bundled
+	// k-NN model is used when neither Path nor Dir is set
+	Path string `yaml:"path"`
+	// Dir holds model artifacts to load into the registry. It takes
+	// precedence over Path
+	Dir string `yaml:"dir"`
+	// Primary is the version serving predictions; the newest when empty
+	Primary string `yaml:"primary"`
+	// Candidate receives CandidateWeight (0 to 1) of the predictions
+	Candidate       string  `yaml:"candidate"`
+	CandidateWeight float64 `yaml:"candidate_weight"`
+}
+
+// MonitorConfig controls prediction logging. Drift statistics are kept for
+// every prediction; only the log is sampled
+type MonitorConfig struct {
+	// SampleRate is the fraction (0 to 1) of predictions written to LogPath
+	SampleRate float64 `yaml:"sample_rate"`
+	// LogPath is a JSON lines file of sampled predictions. Logging is
+	// disabled when it is empty
+	LogPath string `yaml:"log_path"`
+}
+
+// NameConfig controls which names are accepted on the greeting form.
+// Names are NFC normalized before they are checked
+type NameConfig struct {
+	// MaxLength is the maximum number of characters of a name
+	MaxLength int `yaml:"max_length"`
+	// Scripts, if set, restricts letters to these Unicode scripts, such as
+	// "Latin" or "Cyrillic"
+	Scripts []string `yaml:"scripts"`
+}
+
+// PageCacheConfig controls the in-memory cache of rendered pages. Pages
+// still get ETags when nothing is cached
+type PageCacheConfig struct {
+	// MaxBytes bounds the size of the cached pages; nothing is cached when
+	// it is zero
+	MaxBytes   int64         `yaml:"max_bytes"`
+	MaxEntries int           `yaml:"max_entries"`
+	TTL        time.Duration `yaml:"ttl"`
+	// CacheControl overrides the Cache-Control header of cacheable routes,
+	// keyed by route pattern such as "GET /dataset"
+	CacheControl map[string]string `yaml:"cache_control"`
 }
 
 // Options holds configuration options that can override file values
@@ -30,11 +233,16 @@ type Options struct {
 
 // ParseFlags parses command-line flags and returns Options
 func ParseFlags() *Options {
+	return ParseArgs(flag.CommandLine, os.Args[1:])
+}
+
+// ParseArgs registers the config flags on fs and parses args
+func ParseArgs(fs *flag.FlagSet, args []string) *Options {
 	opts := &Options{}
-	flag.StringVar(&opts.ConfigFile, "config", "config.yml", "path to config file")
-	flag.IntVar(&opts.Port, "port", 0, "server port (overrides config file)")
-	flag.DurationVar(&opts.ShutdownTimeout, "shutdown-timeout", 0, "shutdown timeout (overrides config file)")
-	flag.Parse()
+	fs.StringVar(&opts.ConfigFile, "config", "config.yml", "path to config file")
+	fs.IntVar(&opts.Port, "port", 0, "server port (overrides config file)")
+	fs.DurationVar(&opts.ShutdownTimeout, "shutdown-timeout", 0, "shutdown timeout (overrides config file)")
+	fs.Parse(args)
 	return opts
 }
 
@@ -66,5 +274,9 @@ func Load(opts *Options) (*Config, error) {
 		cfg.Server.ShutdownTimeout = opts.ShutdownTimeout
 	}
 
+	if err := cfg.Auth.JWT.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return cfg, nil
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -25,6 +26,15 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Server.ShutdownTimeout != 30*time.Second {
 		t.Errorf("expected shutdown timeout 30s, got %v", cfg.Server.ShutdownTimeout)
 	}
+	if cfg.Monitor.SampleRate != 0.1 {
+		t.Errorf("expected monitoring sample rate 0.1, got %v", cfg.Monitor.SampleRate)
+	}
+	if cfg.Names.MaxLength != 64 {
+		t.Errorf("expected name max length 64, got %d", cfg.Names.MaxLength)
+	}
+	if cfg.PageCache.MaxBytes != 8<<20 || cfg.PageCache.TTL != 10*time.Minute {
+		t.Errorf("unexpected page cache defaults %+v", cfg.PageCache)
+	}
 }
 
 func TestLoadConfigFromFile(t *testing.T) {
@@ -35,6 +45,8 @@ func TestLoadConfigFromFile(t *testing.T) {
   write_timeout: 20s
   idle_timeout: 120s
   shutdown_timeout: 45s
+  dev_mode: true
+  template_dir: web/templates
 `
 	tmpfile, err := os.CreateTemp("", "config-*.yml")
 	if err != nil {
@@ -63,6 +75,9 @@ func TestLoadConfigFromFile(t *testing.T) {
 	if cfg.Server.ShutdownTimeout != 45*time.Second {
 		t.Errorf("expected shutdown timeout 45s, got %v", cfg.Server.ShutdownTimeout)
 	}
+	if !cfg.Server.DevMode || cfg.Server.TemplateDir != "web/templates" {
+		t.Errorf("expected dev mode with web/templates, got %v %q", cfg.Server.DevMode, cfg.Server.TemplateDir)
+	}
 }
 
 func TestLoadConfigWithMissingFile(t *testing.T) {
@@ -218,3 +233,103 @@ func TestParseFlagsDefaults(t *testing.T) {
 		t.Errorf("expected default timeout 0, got %v", opts.ShutdownTimeout)
 	}
 }
+
+func TestLoadConfigAuthSection(t *testing.T) {
+	content := `auth:
+  api_keys_file: /etc/iris/keys.yml
+  api_keys:
+    - name: reporting
+      hash: sha256:abc
+      scopes: [greetings:read]
+      expires_at: 2030-01-02T03:04:05Z
+`
+	tmpfile, err := os.CreateTemp("", "config-*.yml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := Load(&Options{ConfigFile: tmpfile.Name()})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Auth.APIKeysFile != "/etc/iris/keys.yml" {
+		t.Errorf("expected api keys file, got %q", cfg.Auth.APIKeysFile)
+	}
+	if len(cfg.Auth.APIKeys) != 1 {
+		t.Fatalf("expected 1 api key, got %d", len(cfg.Auth.APIKeys))
+	}
+	key := cfg.Auth.APIKeys[0]
+	if key.Name != "reporting" || len(key.Scopes) != 1 || key.Scopes[0] != "greetings:read" {
+		t.Errorf("unexpected api key %+v", key)
+	}
+	if want := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC); !key.ExpiresAt.Equal(want) {
+		t.Errorf("expected expires_at %v, got %v", want, key.ExpiresAt)
+	}
+}
+
+func TestLoadConfigPostgresSection(t *testing.T) {
+	content := `storage:
+  driver: postgres
+  postgres:
+    dsn: postgres://iris@db/iris
+    max_open_conns: 25
+    conn_max_lifetime: 1h
+`
+	tmpfile, err := os.CreateTemp("", "config-*.yml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := Load(&Options{ConfigFile: tmpfile.Name()})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	pg := cfg.Storage.Postgres
+	if cfg.Storage.Driver != "postgres" || pg.DSN != "postgres://iris@db/iris" {
+		t.Errorf("unexpected storage config %+v", cfg.Storage)
+	}
+	if pg.MaxOpenConns != 25 || pg.ConnMaxLifetime != time.Hour {
+		t.Errorf("pool settings not loaded: %+v", pg)
+	}
+	// Unset values keep their defaults
+	if pg.MaxIdleConns != 5 || pg.QueryTimeout != 5*time.Second {
+		t.Errorf("expected default idle conns and query timeout, got %+v", pg)
+	}
+}
+
+func TestLoadConfigJWTRequiresIssuerAndAudience(t *testing.T) {
+	content := `auth:
+  jwt:
+    jwks_url: https://gateway.example/jwks.json
+    issuer: https://gateway.example
+`
+	tmpfile, err := os.CreateTemp("", "config-*.yml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	_, err = Load(&Options{ConfigFile: tmpfile.Name()})
+	if err == nil || !strings.Contains(err.Error(), "auth.jwt.audience") {
+		t.Errorf("Load() = %v, want missing audience error", err)
+	}
+}
@@ -11,6 +11,56 @@ func DefaultConfig() *Config {
 			WriteTimeout:    15 * time.Second,
 			IdleTimeout:     60 * time.Second,
 			ShutdownTimeout: 30 * time.Second,
+			TemplateDir:     "internal/httpapi/templates",
+		},
+		Auth: AuthConfig{
+			OIDC: OIDCConfig{
+				Scopes:       []string{"openid", "profile", "email"},
+				JWKSCacheTTL: time.Hour,
+				RolesClaim:   "roles",
+			},
+			JWT: JWTConfig{
+				ClockSkew:    30 * time.Second,
+				JWKSCacheTTL: time.Hour,
+				Claims: JWTClaimsConfig{
+					Subject: "sub",
+					Name:    "name",
+					Scopes:  "scope",
+					Roles:   "roles",
+				},
+			},
+		},
+		Session: SessionConfig{
+			CookieName:  "iris_session",
+			TTL:         12 * time.Hour,
+			MaxSessions: 10000,
+		},
+		Audit: AuditConfig{
+			MaxBytes: 10 << 20,
+		},
+		Storage: StorageConfig{
+			Driver: "memory",
+			SQLite: SQLiteConfig{
+				Path: "iris.db",
+			},
+			Postgres: PostgresConfig{
+				MaxOpenConns:    10,
+				MaxIdleConns:    5,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
+				QueryTimeout:    5 * time.Second,
+			},
+		},
+		Monitor: MonitorConfig{
+			SampleRate: 0.1,
+		},
+		Names: NameConfig{
+			MaxLength: 64,
+		},
+		PageCache: PageCacheConfig{
+			MaxBytes:   8 << 20,
+			MaxEntries: 256,
+			TTL:        10 * time.Minute,
 		},
 	}
 }
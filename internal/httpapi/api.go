@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+)
+
+// Scopes a bearer token must carry to use the API routes
+const (
+	ScopePredict      = "predict"
+	ScopeModelsRead   = "models:read"
+	ScopeModelsManage = "models:manage"
+)
+
+// WhoAmI returns the authenticated principal as JSON
+func WhoAmI(w http.ResponseWriter, r *http.Request) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		auth.SetChallenge(w.Header(), "", "", "")
+		Error(w, r, http.StatusUnauthorized, "This resource requires an access token.")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing JSON response: %v", err)
+	}
+}
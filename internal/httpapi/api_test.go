@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func TestWhoAmI(t *testing.T) {
+	p := &auth.Principal{ID: "client", Method: auth.MethodAPIKey, Scopes: []string{"greetings:read"}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(WhoAmI).ServeHTTP(rr, req)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	var body auth.Principal
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body.ID != "client" || body.Method != auth.MethodAPIKey {
+		t.Errorf("unexpected body %+v", body)
+	}
+}
+
+func TestWhoAmI_Anonymous(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(WhoAmI).ServeHTTP(rr, req)
+
+	if got, want := rr.Code, http.StatusUnauthorized; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want problem JSON", ct)
+	}
+	if c := rr.Header().Get("WWW-Authenticate"); c == "" {
+		t.Errorf("missing WWW-Authenticate challenge")
+	}
+}
+
+func TestBearerChallengesAsProblemJSON(t *testing.T) {
+	keys, err := auth.NewAPIKeyStore(config.AuthConfig{APIKeys: []config.APIKeyConfig{
+		{Name: "client", Hash: auth.HashAPIKey("good-key")},
+	}})
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() failed: %v", err)
+	}
+	h := auth.Bearer(keys, Error)(http.HandlerFunc(WhoAmI))
+
+	tests := []struct {
+		name      string
+		header    string
+		status    int
+		challenge string
+	}{
+		{"missing", "", http.StatusUnauthorized, `Bearer realm="iris-web-service"`},
+		{"wrong key", "Bearer bad-key", http.StatusUnauthorized, `error="invalid_token"`},
+		{"malformed", "Bearer a b", http.StatusBadRequest, `error="invalid_request"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.status)
+			}
+			if c := rr.Header().Get("WWW-Authenticate"); !strings.Contains(c, tc.challenge) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", c, tc.challenge)
+			}
+			var problem Problem
+			if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil || problem.Status != tc.status {
+				t.Errorf("body is not a problem with status %d: %+v, %v", tc.status, problem, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+const (
+	// defaultMaxBatchBody bounds the request body of a batch prediction
+	defaultMaxBatchBody = 32 << 20
+	// maxNDJSONLine bounds a single NDJSON record
+	maxNDJSONLine = 64 << 10
+	// batchFlushRows is how many result rows are buffered before flushing
+	batchFlushRows = 64
+)
+
+// Batch formats, selected by the request Content-Type. The response uses
+// the same format
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// batchRow is one input record. err is set when the record itself is
+// invalid; the rest of the batch is still scored
+type batchRow struct {
+	row      int
+	id       string
+	features iris.Features
+	err      error
+}
+
+// batchResult is written for every input record
+type batchResult struct {
+	Row           int                `json:"row"`
+	ID            string             `json:"id,omitempty"`
+	Species       string             `json:"species,omitempty"`
+	Probabilities map[string]float64 `json:"probabilities,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// batchReader yields input records until io.EOF. Any other error aborts
+// the batch
+type batchReader interface {
+	next() (batchRow, error)
+}
+
+type batchWriter interface {
+	write(res batchResult) error
+	flush() error
+}
+
+// PredictBatch scores a CSV or NDJSON body row by row, streaming the
+// results back in the same format. CSV bodies need a header naming the
+// four measurements and may carry an id column that is echoed back
+func (h *Handlers) PredictBatch(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	limit := h.maxBatchBody
+	if limit <= 0 {
+		limit = defaultMaxBatchBody
+	}
+	body := http.MaxBytesReader(w, r.Body, limit)
+
+	var in batchReader
+	var out batchWriter
+	switch mediaType {
+	case ContentTypeCSV:
+		cr, err := newCSVBatchReader(body)
+		if err != nil {
+			batchReadError(w, r, err)
+			return
+		}
+		in, out = cr, newCSVBatchWriter(w)
+	case ContentTypeNDJSON, "application/ndjson":
+		in, out = newNDJSONBatchReader(body), newNDJSONBatchWriter(w)
+		mediaType = ContentTypeNDJSON
+	default:
+		Error(w, r, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Content-Type must be %s or %s.", ContentTypeCSV, ContentTypeNDJSON))
+		return
+	}
+
+	// HTTP/1.x servers stop reading the body once the response starts
+	// unless full duplex is enabled. HTTP/2 is always full duplex
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil && r.ProtoMajor == 1 {
+		log.Printf("batch: enabling full duplex: %v", err)
+	}
+	// The whole batch is scored by one model so that results are
+	// comparable
+	model := h.model(w)
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	rows := 0
+	for {
+		if err := r.Context().Err(); err != nil {
+			log.Printf("batch: client went away after %d rows: %v", rows, err)
+			return
+		}
+
+		row, err := in.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// The status is already sent, so report the failure in band
+			out.write(batchResult{Row: rows + 1, Error: batchErrorMessage(err)})
+			break
+		}
+		rows++
+
+		res := batchResult{Row: row.row, ID: row.id}
+		if row.err == nil {
+			row.err = row.features.Validate()
+		}
+		if row.err != nil {
+			res.Error = row.err.Error()
+		} else {
+			p := model.Classifier.Predict(row.features)
+			h.observe(model, row.features, p)
+			res.Species, res.Probabilities = p.Species, p.Probabilities
+		}
+		if err := out.write(res); err != nil {
+			log.Printf("batch: writing row %d: %v", row.row, err)
+			return
+		}
+		if rows%batchFlushRows == 0 {
+			out.flush()
+			rc.Flush()
+		}
+	}
+	out.flush()
+	rc.Flush()
+}
+
+// batchReadError rejects a batch whose header could not be read
+func batchReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Error(w, r, http.StatusRequestEntityTooLarge, "Request body too large.")
+		return
+	}
+	Error(w, r, http.StatusBadRequest, err.Error())
+}
+
+func batchErrorMessage(err error) string {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Sprintf("request body exceeds %d bytes; batch truncated", tooLarge.Limit)
+	}
+	return "batch aborted: " + err.Error()
+}
+
+type csvBatchReader struct {
+	cr   *csv.Reader
+	cols []int
+	id   int
+	row  int
+}
+
+func newCSVBatchReader(body io.Reader) (*csvBatchReader, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty CSV body")
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	br := &csvBatchReader{cr: cr, id: -1}
+	for _, name := range iris.FeatureNames {
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("CSV header is missing column %q", name)
+		}
+		br.cols = append(br.cols, i)
+	}
+	if i, ok := index["id"]; ok {
+		br.id = i
+	}
+	return br, nil
+}
+
+func (b *csvBatchReader) next() (batchRow, error) {
+	rec, err := b.cr.Read()
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		b.row++
+		return batchRow{row: b.row, err: perr.Err}, nil
+	}
+	if err != nil {
+		return batchRow{}, err
+	}
+	b.row++
+
+	row := batchRow{row: b.row}
+	if b.id >= 0 && b.id < len(rec) {
+		row.id = rec[b.id]
+	}
+	var v [4]float64
+	for i, col := range b.cols {
+		if col >= len(rec) {
+			row.err = fmt.Errorf("missing %s", iris.FeatureNames[i])
+			return row, nil
+		}
+		v[i], err = strconv.ParseFloat(strings.TrimSpace(rec[col]), 64)
+		if err != nil {
+			row.err = fmt.Errorf("%s must be a number", iris.FeatureNames[i])
+			return row, nil
+		}
+	}
+	row.features = iris.Features{SepalLength: v[0], SepalWidth: v[1], PetalLength: v[2], PetalWidth: v[3]}
+	return row, nil
+}
+
+type csvBatchWriter struct {
+	cw *csv.Writer
+}
+
+func newCSVBatchWriter(w io.Writer) *csvBatchWriter {
+	cw := csv.NewWriter(w)
+	header := []string{"row", "id", "species"}
+	for _, s := range iris.Species {
+		header = append(header, "prob_"+s)
+	}
+	cw.Write(append(header, "error"))
+	return &csvBatchWriter{cw: cw}
+}
+
+func (b *csvBatchWriter) write(res batchResult) error {
+	rec := []string{strconv.Itoa(res.Row), res.ID, res.Species}
+	for _, s := range iris.Species {
+		if res.Probabilities == nil {
+			rec = append(rec, "")
+			continue
+		}
+		rec = append(rec, strconv.FormatFloat(res.Probabilities[s], 'f', 4, 64))
+	}
+	return b.cw.Write(append(rec, res.Error))
+}
+
+func (b *csvBatchWriter) flush() error {
+	b.cw.Flush()
+	return b.cw.Error()
+}
+
+type ndjsonBatchReader struct {
+	br  *bufio.Reader
+	row int
+}
+
+func newNDJSONBatchReader(body io.Reader) *ndjsonBatchReader {
+	return &ndjsonBatchReader{br: bufio.NewReaderSize(body, maxNDJSONLine)}
+}
+
+func (b *ndjsonBatchReader) next() (batchRow, error) {
+	for {
+		// Unlike bufio.Scanner, ReadSlice does not hand out the fragment
+		// left when the body is cut off by the size limit
+		line, err := b.br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if err := b.skipLine(); err != nil {
+				return batchRow{}, err
+			}
+			b.row++
+			return batchRow{row: b.row, err: fmt.Errorf("record is longer than %d bytes", maxNDJSONLine)}, nil
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return batchRow{}, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		b.row++
+
+		var rec struct {
+			ID json.RawMessage `json:"id"`
+			iris.Features
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			return batchRow{row: b.row, err: fmt.Errorf("invalid JSON: %v", err)}, nil
+		}
+		return batchRow{row: b.row, id: rawID(rec.ID), features: rec.Features}, nil
+	}
+}
+
+// skipLine discards the rest of an oversized record. Reaching the end of
+// the body is not an error, since the record still gets its own row
+func (b *ndjsonBatchReader) skipLine() error {
+	for {
+		_, err := b.br.ReadSlice('\n')
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		}
+		return err
+	}
+}
+
+// rawID echoes a string or numeric id back as text
+func rawID(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+type ndjsonBatchWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONBatchWriter(w io.Writer) *ndjsonBatchWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonBatchWriter{bw: bw, enc: json.NewEncoder(bw)}
+}
+
+func (b *ndjsonBatchWriter) write(res batchResult) error {
+	return b.enc.Encode(res)
+}
+
+func (b *ndjsonBatchWriter) flush() error {
+	return b.bw.Flush()
+}
@@ -0,0 +1,206 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postBatch(t *testing.T, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return postBatchTo(t, &Handlers{}, contentType, body)
+}
+
+func postBatchTo(t *testing.T, h *Handlers, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/predict/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.PredictBatch).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPredictBatchCSV(t *testing.T) {
+	body := "id,petal_width,petal_length,sepal_width,sepal_length\n" +
+		"a,0.2,1.4,3.5,5.1\n" +
+		"b,2.5,6.0,3.3,x\n" +
+		"c,2.5,99,3.3,6.3\n" +
+		"d,2.5,6.0,3.3,6.3\n"
+	rr := postBatch(t, "text/csv; charset=utf-8", body)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d: %s", got, want, rr.Body)
+	}
+	if got := rr.Header().Get("Content-Type"); got != ContentTypeCSV {
+		t.Errorf("content-type = %q, want %q", got, ContentTypeCSV)
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV response: %v", err)
+	}
+	want := [][]string{
+		{"row", "id", "species", "prob_setosa", "prob_versicolor", "prob_virginica", "error"},
+		{"1", "a", "setosa", "1.0000", "0.0000", "0.0000", ""},
+		{"2", "b", "", "", "", "", "sepal_length must be a number"},
+		{"3", "c", "", "", "", "", "petal_length must be greater than 0 and at most 15 cm"},
+		{"4", "d", "virginica", "0.0000", "0.0000", "1.0000", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("record %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+func TestPredictBatchNDJSON(t *testing.T) {
+	body := `{"id": 7, "sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}` + "\n" +
+		"\n" +
+		`{"sepal_length": "long"}` + "\n" +
+		`{"id": "x", "sepal_length": 6.3, "sepal_width": 3.3, "petal_length": 6.0, "petal_width": 2.5}`
+	rr := postBatch(t, "application/x-ndjson", body)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	var results []batchResult
+	sc := bufio.NewScanner(rr.Body)
+	for sc.Scan() {
+		var res batchResult
+		if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		results = append(results, res)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Row != 1 || r.ID != "7" || r.Species != "setosa" {
+		t.Errorf("unexpected first result %+v", r)
+	}
+	if r := results[1]; r.Row != 2 || r.Error == "" || r.Species != "" {
+		t.Errorf("expected a row error, got %+v", r)
+	}
+	if r := results[2]; r.Row != 3 || r.ID != "x" || r.Species != "virginica" || r.Probabilities["virginica"] != 1 {
+		t.Errorf("unexpected last result %+v", r)
+	}
+}
+
+func TestPredictBatchNDJSONLongLine(t *testing.T) {
+	row := `{"sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}` + "\n"
+	long := `{"id": "` + strings.Repeat("x", 2*maxNDJSONLine) + `"}` + "\n"
+	for _, body := range []string{row + long + row, row + long} {
+		rr := postBatch(t, ContentTypeNDJSON, body)
+
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		if len(lines) != strings.Count(body, "\n") {
+			t.Fatalf("got %d results, want %d: %s", len(lines), strings.Count(body, "\n"), rr.Body)
+		}
+		for i, line := range lines {
+			var res batchResult
+			if err := json.Unmarshal([]byte(line), &res); err != nil {
+				t.Fatalf("invalid NDJSON line %q: %v", line, err)
+			}
+			if res.Row != i+1 {
+				t.Errorf("row = %d, want %d", res.Row, i+1)
+			}
+			if i == 1 && !strings.Contains(res.Error, "longer than") {
+				t.Errorf("expected an oversized record error, got %+v", res)
+			}
+			if i != 1 && res.Species != "setosa" {
+				t.Errorf("unexpected result %+v", res)
+			}
+		}
+	}
+}
+
+func TestPredictBatchRejectsRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"unsupported type", "application/json", `[]`, http.StatusUnsupportedMediaType},
+		{"empty csv", "text/csv", ``, http.StatusBadRequest},
+		{"missing column", "text/csv", "sepal_length,sepal_width,petal_length\n", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postBatch(t, tt.contentType, tt.body)
+			if got := rr.Code; got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+			if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("content-type = %q, want application/problem+json", got)
+			}
+		})
+	}
+}
+
+func TestPredictBatchBodyLimit(t *testing.T) {
+	row := `{"sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}` + "\n"
+	h := &Handlers{maxBatchBody: 1000}
+	rr := postBatchTo(t, h, ContentTypeNDJSON, strings.Repeat(row, 20))
+
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	var last batchResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("invalid last line: %v", err)
+	}
+	if !strings.Contains(last.Error, "truncated") {
+		t.Errorf("last line = %+v, want a truncation error", last)
+	}
+	// Every complete row within the limit is scored; the fragment cut off
+	// by it is not
+	for _, line := range lines[:len(lines)-1] {
+		if !strings.Contains(line, `"species":"setosa"`) {
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	if got, want := len(lines)-1, 1000/len(row); got != want {
+		t.Errorf("scored %d rows, want %d", got, want)
+	}
+}
+
+func TestPredictBatchStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc((&Handlers{}).PredictBatch))
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, pr)
+	req.Header.Set("Content-Type", ContentTypeNDJSON)
+
+	row := `{"sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}` + "\n"
+	go func() {
+		// Send one flush worth of rows and keep the body open
+		pw.Write([]byte(strings.Repeat(row, batchFlushRows)))
+	}()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The first results arrive before the request body is complete
+	sc := bufio.NewScanner(resp.Body)
+	if !sc.Scan() {
+		t.Fatalf("no streamed result: %v", sc.Err())
+	}
+	if !strings.Contains(sc.Text(), `"species":"setosa"`) {
+		t.Errorf("unexpected first line %q", sc.Text())
+	}
+	pw.Close()
+}
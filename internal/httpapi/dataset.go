@@ -0,0 +1,399 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+// Scatter plot geometry of dataset.html, in SVG user units
+const (
+	plotSize   = 360
+	plotMargin = 40
+)
+
+// speciesColors are the scatter plot colours; other species are grey
+var speciesColors = map[string]string{
+	iris.Setosa:     "#e74c3c",
+	iris.Versicolor: "#27ae60",
+	iris.Virginica:  "#2980b9",
+}
+
+// DatasetData is rendered by dataset.html
+type DatasetData struct {
+	// Values holds the query parameters so the filter form can show them
+	Values    map[string]string
+	Species   []string
+	Features  []FeatureOption
+	Rows      []DatasetRow
+	Total     int
+	Summaries []SpeciesSummary
+	Plot      ScatterPlot
+	// SortURL maps a column to the link that sorts by it
+	SortURL    map[string]string
+	CSVURL     string
+	JSONURL    string
+	SortColumn string
+	SortDesc   bool
+}
+
+type FeatureOption struct {
+	Name  string
+	Label string
+}
+
+// DatasetRow is a sample with its 1-based position in the dataset
+type DatasetRow struct {
+	N int
+	iris.Sample
+}
+
+// SpeciesSummary describes the filtered samples of one species
+type SpeciesSummary struct {
+	Species string
+	Count   int
+	Stats   []FeatureSummary
+}
+
+type FeatureSummary struct {
+	Name                string
+	Mean, Std, Min, Max float64
+}
+
+// ScatterPlot plots one feature against another
+type ScatterPlot struct {
+	X, Y           FeatureOption
+	Size           int
+	XTicks, YTicks []Tick
+	Points         []ScatterPoint
+	Legend         []LegendEntry
+}
+
+type Tick struct {
+	Pos   float64
+	Label string
+}
+
+type ScatterPoint struct {
+	CX, CY float64
+	Color  string
+	Title  string
+}
+
+type LegendEntry struct {
+	Species string
+	Color   string
+}
+
+// datasetQuery is the filter, order and plot selected by the query string
+type datasetQuery struct {
+	species  string
+	min, max [4]float64
+	sort     string
+	desc     bool
+	x, y     int
+	values   map[string]string
+}
+
+func parseDatasetQuery(q url.Values) (datasetQuery, error) {
+	dq := datasetQuery{
+		species: q.Get("species"),
+		sort:    q.Get("sort"),
+		x:       slices.Index(iris.FeatureNames, "petal_length"),
+		y:       slices.Index(iris.FeatureNames, "petal_width"),
+		values:  make(map[string]string),
+	}
+	for key := range q {
+		dq.values[key] = q.Get(key)
+	}
+
+	if dq.species != "" && !slices.Contains(iris.Species, dq.species) {
+		return dq, fmt.Errorf("unknown species %q", dq.species)
+	}
+	if dq.sort != "" && dq.sort != "species" && !slices.Contains(iris.FeatureNames, dq.sort) {
+		return dq, fmt.Errorf("cannot sort by %q", dq.sort)
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		dq.desc = true
+	default:
+		return dq, errors.New("order must be asc or desc")
+	}
+
+	for i, name := range iris.FeatureNames {
+		var err error
+		if dq.min[i], err = parseBound(q, "min_"+name, math.Inf(-1)); err != nil {
+			return dq, err
+		}
+		if dq.max[i], err = parseBound(q, "max_"+name, math.Inf(1)); err != nil {
+			return dq, err
+		}
+	}
+	for _, axis := range []struct {
+		key string
+		dst *int
+	}{{"x", &dq.x}, {"y", &dq.y}} {
+		if name := q.Get(axis.key); name != "" {
+			if *axis.dst = slices.Index(iris.FeatureNames, name); *axis.dst < 0 {
+				return dq, fmt.Errorf("unknown feature %q for %s", name, axis.key)
+			}
+		}
+	}
+	return dq, nil
+}
+
+// parseBound reads an optional numeric filter, defaulting to def
+func parseBound(q url.Values, key string, def float64) (float64, error) {
+	raw := strings.TrimSpace(q.Get(key))
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) {
+		return 0, fmt.Errorf("%s must be a number", key)
+	}
+	return v, nil
+}
+
+// rows returns the matching samples in the requested order
+func (dq datasetQuery) rows() []DatasetRow {
+	var rows []DatasetRow
+	for i, s := range iris.Dataset() {
+		if dq.matches(s) {
+			rows = append(rows, DatasetRow{N: i + 1, Sample: s})
+		}
+	}
+	if dq.sort == "" {
+		return rows
+	}
+	col := slices.Index(iris.FeatureNames, dq.sort)
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if dq.desc {
+			a, b = b, a
+		}
+		if col < 0 {
+			return a.Species < b.Species
+		}
+		return a.Vector()[col] < b.Vector()[col]
+	})
+	return rows
+}
+
+// samples returns the matching samples without their positions
+func (dq datasetQuery) samples() []iris.Sample {
+	samples := []iris.Sample{}
+	for _, row := range dq.rows() {
+		samples = append(samples, row.Sample)
+	}
+	return samples
+}
+
+func (dq datasetQuery) matches(s iris.Sample) bool {
+	if dq.species != "" && s.Species != dq.species {
+		return false
+	}
+	for i, v := range s.Vector() {
+		if v < dq.min[i] || v > dq.max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// url links to path with the current filter, overridden by extra
+func (dq datasetQuery) url(path string, extra map[string]string) string {
+	q := url.Values{}
+	for k, v := range dq.values {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	for k, v := range extra {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// DatasetPage shows the bundled dataset with filtering, sorting, per-species
+// statistics and a scatter plot
+func (h *Handlers) DatasetPage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("DatasetPage called: %s %s", r.Method, r.URL.Path)
+
+	dq, err := parseDatasetQuery(r.URL.Query())
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows := dq.rows()
+
+	data := DatasetData{
+		Values:     dq.values,
+		Species:    iris.Species,
+		Rows:       rows,
+		Total:      len(iris.Dataset()),
+		Summaries:  summarize(rows),
+		Plot:       scatterPlot(rows, dq.x, dq.y),
+		SortURL:    make(map[string]string),
+		CSVURL:     dq.url("/dataset.csv", map[string]string{"x": "", "y": ""}),
+		JSONURL:    dq.url("/dataset.json", map[string]string{"x": "", "y": ""}),
+		SortColumn: dq.sort,
+		SortDesc:   dq.desc,
+	}
+	for _, name := range iris.FeatureNames {
+		data.Features = append(data.Features, featureOption(name))
+	}
+	for _, col := range append(slices.Clone(iris.FeatureNames), "species") {
+		order := "asc"
+		if col == dq.sort && !dq.desc {
+			order = "desc"
+		}
+		data.SortURL[col] = dq.url("/dataset", map[string]string{"sort": col, "order": order})
+	}
+	Render(w, r, "dataset.html", data)
+}
+
+// DatasetCSV downloads the filtered dataset as CSV
+func (h *Handlers) DatasetCSV(w http.ResponseWriter, r *http.Request) {
+	dq, err := parseDatasetQuery(r.URL.Query())
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="iris.csv"`)
+	if err := iris.WriteCSV(w, dq.samples()); err != nil {
+		log.Printf("writing dataset CSV: %v", err)
+	}
+}
+
+// DatasetJSON downloads the filtered dataset as a JSON array
+func (h *Handlers) DatasetJSON(w http.ResponseWriter, r *http.Request) {
+	dq, err := parseDatasetQuery(r.URL.Query())
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="iris.json"`)
+	writeJSON(w, http.StatusOK, dq.samples())
+}
+
+func featureOption(name string) FeatureOption {
+	label := strings.ReplaceAll(name, "_", " ")
+	return FeatureOption{Name: name, Label: strings.ToUpper(label[:1]) + label[1:]}
+}
+
+// summarize computes per-species statistics in iris.Species order
+func summarize(rows []DatasetRow) []SpeciesSummary {
+	bySpecies := make(map[string][]iris.Sample)
+	for _, row := range rows {
+		bySpecies[row.Species] = append(bySpecies[row.Species], row.Sample)
+	}
+	var out []SpeciesSummary
+	for _, species := range iris.Species {
+		samples := bySpecies[species]
+		if len(samples) == 0 {
+			continue
+		}
+		sum := SpeciesSummary{Species: species, Count: len(samples)}
+		for i, name := range iris.FeatureNames {
+			fs := FeatureSummary{Name: name, Min: math.Inf(1), Max: math.Inf(-1)}
+			for _, s := range samples {
+				v := s.Vector()[i]
+				fs.Mean += v
+				fs.Min = math.Min(fs.Min, v)
+				fs.Max = math.Max(fs.Max, v)
+			}
+			fs.Mean /= float64(len(samples))
+			if len(samples) > 1 {
+				for _, s := range samples {
+					d := s.Vector()[i] - fs.Mean
+					fs.Std += d * d
+				}
+				fs.Std = math.Sqrt(fs.Std / float64(len(samples)-1))
+			}
+			sum.Stats = append(sum.Stats, fs)
+		}
+		out = append(out, sum)
+	}
+	return out
+}
+
+// scatterPlot plots feature y against feature x. The axes span the whole
+// dataset so that filtered views stay comparable
+func scatterPlot(rows []DatasetRow, x, y int) ScatterPlot {
+	p := ScatterPlot{
+		X:    featureOption(iris.FeatureNames[x]),
+		Y:    featureOption(iris.FeatureNames[y]),
+		Size: plotSize + 2*plotMargin,
+	}
+	xlo, xhi := axisRange(x)
+	ylo, yhi := axisRange(y)
+	xpos := func(v float64) float64 { return plotMargin + plotSize*(v-xlo)/(xhi-xlo) }
+	ypos := func(v float64) float64 { return plotMargin + plotSize*(yhi-v)/(yhi-ylo) }
+	p.XTicks = ticks(xlo, xhi, xpos)
+	p.YTicks = ticks(ylo, yhi, ypos)
+
+	for _, row := range rows {
+		v := row.Vector()
+		color, ok := speciesColors[row.Species]
+		if !ok {
+			color = "#7f8c8d"
+		}
+		p.Points = append(p.Points, ScatterPoint{
+			CX:    xpos(v[x]),
+			CY:    ypos(v[y]),
+			Color: color,
+			Title: fmt.Sprintf("#%d %s: %g × %g", row.N, row.Species, v[x], v[y]),
+		})
+	}
+	for _, species := range iris.Species {
+		p.Legend = append(p.Legend, LegendEntry{Species: species, Color: speciesColors[species]})
+	}
+	return p
+}
+
+// axisRange rounds the dataset range of feature i out to whole centimetres
+func axisRange(i int) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, s := range iris.Dataset() {
+		lo = math.Min(lo, s.Vector()[i])
+		hi = math.Max(hi, s.Vector()[i])
+	}
+	lo, hi = math.Floor(lo), math.Ceil(hi)
+	if hi == lo {
+		hi++
+	}
+	return lo, hi
+}
+
+// ticks places a tick every centimetre, or every half when the axis is
+// short
+func ticks(lo, hi float64, pos func(float64) float64) []Tick {
+	step := 1.0
+	if hi-lo <= 3 {
+		step = 0.5
+	}
+	var out []Tick
+	for v := lo; v <= hi+1e-9; v += step {
+		out = append(out, Tick{Pos: pos(v), Label: strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+	return out
+}
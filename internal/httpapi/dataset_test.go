@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+func getDataset(t *testing.T, handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+func TestDatasetPage(t *testing.T) {
+	h := &Handlers{}
+	rr := getDataset(t, h.DatasetPage, "/dataset")
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"150 of 150 flowers", "<circle", "setosa (50)", "Petal width against Petal length"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+
+	rr = getDataset(t, h.DatasetPage, "/dataset?species=virginica&min_petal_length=6&sort=sepal_width&order=desc&x=sepal_length&y=sepal_width")
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body = rr.Body.String()
+	for _, want := range []string{"11 of 150 flowers", "virginica (11)", "Sepal width against Sepal length", `value="6"`,
+		`href="/dataset.csv?min_petal_length=6&amp;order=desc&amp;sort=sepal_width&amp;species=virginica"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("filtered page missing %q", want)
+		}
+	}
+	if strings.Contains(body, "setosa (") {
+		t.Error("filtered page summarizes setosa")
+	}
+}
+
+func TestDatasetPageRejectsQuery(t *testing.T) {
+	for _, query := range []string{
+		"species=rose",
+		"sort=colour",
+		"order=sideways",
+		"min_sepal_length=abc",
+		"x=stem_length",
+	} {
+		rr := getDataset(t, (&Handlers{}).DatasetPage, "/dataset?"+query)
+		if got, want := rr.Code, http.StatusBadRequest; got != want {
+			t.Errorf("%s: status = %d, want %d", query, got, want)
+		}
+	}
+}
+
+func TestDatasetQueryRows(t *testing.T) {
+	dq, err := parseDatasetQuery(url.Values{"sort": {"petal_length"}, "order": {"desc"}, "max_sepal_width": {"2.5"}})
+	if err != nil {
+		t.Fatalf("parseDatasetQuery() failed: %v", err)
+	}
+	rows := dq.rows()
+	if len(rows) == 0 {
+		t.Fatal("no rows")
+	}
+	for i, row := range rows {
+		if row.SepalWidth > 2.5 {
+			t.Errorf("row %d has sepal width %v above the filter", row.N, row.SepalWidth)
+		}
+		if i > 0 && row.PetalLength > rows[i-1].PetalLength {
+			t.Errorf("rows not sorted by descending petal length at %d", i)
+		}
+		if iris.Dataset()[row.N-1] != row.Sample {
+			t.Errorf("row %d does not match dataset position", row.N)
+		}
+	}
+}
+
+func TestDatasetDownloads(t *testing.T) {
+	h := &Handlers{}
+	rr := getDataset(t, h.DatasetCSV, "/dataset.csv?species=setosa")
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, ContentTypeCSV) {
+		t.Errorf("Content-Type = %q, want %s", ct, ContentTypeCSV)
+	}
+	samples, err := iris.ReadCSV(rr.Body)
+	if err != nil || len(samples) != 50 || samples[0] != iris.Dataset()[0] {
+		t.Errorf("CSV download = %d samples, %v", len(samples), err)
+	}
+
+	rr = getDataset(t, h.DatasetJSON, "/dataset.json?species=versicolor&max_petal_length=3.0")
+	var got []iris.Sample
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got) != 1 || got[0].Species != iris.Versicolor {
+		t.Errorf("JSON download = %+v", got)
+	}
+
+	rr = getDataset(t, h.DatasetJSON, "/dataset.json?species=rose")
+	if got, want := rr.Code, http.StatusBadRequest; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var rows []DatasetRow
+	for i, s := range iris.Dataset() {
+		rows = append(rows, DatasetRow{N: i + 1, Sample: s})
+	}
+	sums := summarize(rows)
+	if len(sums) != 3 || sums[0].Species != iris.Setosa || sums[0].Count != 50 {
+		t.Fatalf("summarize() = %+v", sums)
+	}
+	// Published values for setosa sepal length
+	sl := sums[0].Stats[0]
+	if math.Abs(sl.Mean-5.006) > 1e-9 || math.Abs(sl.Std-0.3525) > 1e-4 || sl.Min != 4.3 || sl.Max != 5.8 {
+		t.Errorf("setosa sepal length = %+v", sl)
+	}
+}
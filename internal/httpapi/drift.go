@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/monitor"
+)
+
+// PermMonitoringRead allows viewing the drift page
+const PermMonitoringRead = "monitoring:read"
+
+// Chart geometry of drift.html, in SVG user units
+const (
+	chartWidth  = 320
+	chartHeight = 120
+	// psiScaleMin keeps the thresholds visible while drift is low
+	psiScaleMin = 0.3
+)
+
+// DriftData is rendered by drift.html
+type DriftData struct {
+	Models []DriftModel
+}
+
+// DriftModel charts the drift of one model
+type DriftModel struct {
+	Version      string
+	Observations int64
+	PSI          PSIChart
+	Features     []DriftFeature
+}
+
+// PSIChart is a horizontal bar chart of the PSI of each feature
+type PSIChart struct {
+	Bars         []PSIBar
+	Height       int
+	ModerateX    float64
+	SignificantX float64
+}
+
+type PSIBar struct {
+	Name   string
+	PSI    float64
+	Status string
+	Y      int
+	Width  float64
+}
+
+// DriftFeature is a histogram of the training and live inputs of one
+// feature
+type DriftFeature struct {
+	monitor.FeatureDrift
+	Bars []HistogramBar
+}
+
+type HistogramBar struct {
+	Label          string
+	X, Width       float64
+	ExpectedY      float64
+	ExpectedHeight float64
+	ActualX        float64
+	ActualY        float64
+	ActualHeight   float64
+}
+
+// observe hands a served prediction to the monitor
+func (h *Handlers) observe(e *iris.Entry, f iris.Features, p iris.Prediction) {
+	if err := h.Monitor.Observe(e, f, p); err != nil {
+		log.Printf("monitor: %v", err)
+	}
+}
+
+// DriftPage charts how far live inputs have drifted from the data each
+// model was trained on. The route must require PermMonitoringRead
+func (h *Handlers) DriftPage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("DriftPage called: %s %s", r.Method, r.URL.Path)
+
+	var data DriftData
+	for _, md := range h.Monitor.Snapshot() {
+		data.Models = append(data.Models, newDriftModel(md))
+	}
+	Render(w, r, "drift.html", data)
+}
+
+func newDriftModel(md monitor.ModelDrift) DriftModel {
+	dm := DriftModel{Version: md.Version, Observations: md.Observations}
+
+	scale := psiScaleMin
+	for _, d := range md.Features {
+		scale = math.Max(scale, d.PSI)
+	}
+	dm.PSI = PSIChart{
+		Height:       20 * len(md.Features),
+		ModerateX:    chartWidth * monitor.PSIModerate / scale,
+		SignificantX: chartWidth * monitor.PSISignificant / scale,
+	}
+	for i, d := range md.Features {
+		dm.PSI.Bars = append(dm.PSI.Bars, PSIBar{
+			Name:   d.Name,
+			PSI:    d.PSI,
+			Status: d.Status(),
+			Y:      20 * i,
+			Width:  chartWidth * d.PSI / scale,
+		})
+		dm.Features = append(dm.Features, DriftFeature{FeatureDrift: d, Bars: histogramBars(d)})
+	}
+	return dm
+}
+
+func histogramBars(d monitor.FeatureDrift) []HistogramBar {
+	top := 0.0
+	for i := range d.Expected {
+		top = math.Max(top, math.Max(d.Expected[i], d.Actual[i]))
+	}
+	if top == 0 {
+		top = 1
+	}
+	slot := float64(chartWidth) / float64(len(d.Expected))
+	bars := make([]HistogramBar, len(d.Expected))
+	for i := range d.Expected {
+		eh := chartHeight * d.Expected[i] / top
+		ah := chartHeight * d.Actual[i] / top
+		bars[i] = HistogramBar{
+			Label:          binLabel(d.Edges, i),
+			X:              slot*float64(i) + 1,
+			Width:          slot/2 - 1,
+			ExpectedY:      chartHeight - eh,
+			ExpectedHeight: eh,
+			ActualX:        slot*float64(i) + slot/2,
+			ActualY:        chartHeight - ah,
+			ActualHeight:   ah,
+		}
+	}
+	return bars
+}
+
+// binLabel describes the range of bin i in centimetres
+func binLabel(edges []float64, i int) string {
+	switch {
+	case len(edges) == 0:
+		return "all"
+	case i == 0:
+		return fmt.Sprintf("< %g", edges[0])
+	case i == len(edges):
+		return fmt.Sprintf("≥ %g", edges[i-1])
+	}
+	return fmt.Sprintf("%g – %g", edges[i-1], edges[i])
+}
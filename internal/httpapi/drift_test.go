@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/monitor"
+)
+
+func newDriftHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	m, err := monitor.New(config.MonitorConfig{})
+	if err != nil {
+		t.Fatalf("monitor.New() failed: %v", err)
+	}
+	return &Handlers{Monitor: m}
+}
+
+func TestPredictionsAreMonitored(t *testing.T) {
+	h := newDriftHandlers(t)
+	sendAs(http.HandlerFunc(h.PredictAPI), nil, http.MethodPost, "/api/v1/predict",
+		`{"sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}`)
+	postBatchTo(t, h, ContentTypeNDJSON, `{"sepal_length": 6.3, "sepal_width": 3.3, "petal_length": 6.0, "petal_width": 2.5}`+"\n")
+
+	snap := h.Monitor.Snapshot()
+	if len(snap) != 1 || snap[0].Version != iris.BundledVersion || snap[0].Observations != 2 {
+		t.Errorf("Snapshot() = %+v, want 2 observations of %s", snap, iris.BundledVersion)
+	}
+}
+
+func TestDriftPage(t *testing.T) {
+	h := newDriftHandlers(t)
+	e := iris.BundledEntry()
+	for _, s := range iris.Dataset()[:50] {
+		h.observe(&e, s.Features, e.Classifier.Predict(s.Features))
+	}
+	rt := NewRouter(allowList{"ops": {PermMonitoringRead}})
+	rt.HandleFunc("GET /admin/drift", h.DriftPage, RequirePermission(PermMonitoringRead))
+
+	rr := sendAs(rt, &auth.Principal{ID: "ops"}, http.MethodGet, "/admin/drift", "")
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"Model bundled-knn", "50 predictions observed", "<svg", "significant"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+
+	rr = sendAs(rt, &auth.Principal{ID: "user"}, http.MethodGet, "/admin/drift", "")
+	if got, want := rr.Code, http.StatusForbidden; got != want {
+		t.Errorf("status without permission = %d, want %d", got, want)
+	}
+}
+
+func TestDriftPageEmpty(t *testing.T) {
+	rr := sendAs(http.HandlerFunc((&Handlers{}).DriftPage), nil, http.MethodGet, "/admin/drift", "")
+	if !strings.Contains(rr.Body.String(), "No predictions observed yet") {
+		t.Errorf("unexpected body %q", rr.Body.String())
+	}
+}
+
+func TestBinLabel(t *testing.T) {
+	edges := []float64{1.5, 4.35}
+	tests := []struct {
+		bin  int
+		want string
+	}{
+		{0, "< 1.5"},
+		{1, "1.5 – 4.35"},
+		{2, "≥ 4.35"},
+	}
+	for _, tt := range tests {
+		if got := binLabel(edges, tt.bin); got != tt.want {
+			t.Errorf("binLabel(%d) = %q, want %q", tt.bin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+// Problem is an RFC 9457 problem details document
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type ErrorData struct {
+	Status int
+	Title  string
+	// Hint explains the status in plain words, when the catalog has one
+	Hint      string
+	Detail    string
+	RequestID string
+}
+
+// newErrorData describes status in the language of r
+func newErrorData(r *http.Request, status int, detail string) ErrorData {
+	c := i18n.FromContext(r.Context())
+	data := ErrorData{
+		Status:    status,
+		Title:     http.StatusText(status),
+		Detail:    detail,
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+	if key := fmt.Sprintf("error.%d.title", status); c.Has(key) {
+		data.Title = c.T(key)
+	}
+	if key := fmt.Sprintf("error.%d.hint", status); c.Has(key) {
+		data.Hint = c.T(key)
+	}
+	return data
+}
+
+// Error writes an error response: problem JSON for API clients and the
+// themed error page for browsers
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	requestID := RequestIDFromContext(r.Context())
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		err := json.NewEncoder(w).Encode(Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    detail,
+			Instance:  r.URL.Path,
+			RequestID: requestID,
+		})
+		if err != nil {
+			log.Printf("writing problem response: %v", err)
+		}
+		return
+	}
+
+	RenderStatus(w, r, status, "error.html", newErrorData(r, status, detail))
+}
+
+// wantsJSON reports whether the client should get a machine readable error:
+// everything under /api/ and any request that prefers JSON over HTML
+func wantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		return false
+	}
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "application/problem+json")
+}
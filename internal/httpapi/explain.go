@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+// Explanation chart geometry of predict.html, in SVG user units
+const (
+	explainLabelWidth = 120
+	explainBarWidth   = 240
+	explainRowHeight  = 20
+)
+
+// ExplanationChart is a horizontal bar chart of an explanation. Bars
+// start at ZeroX and extend left for negative values
+type ExplanationChart struct {
+	Method string
+	Width  int
+	Height int
+	ZeroX  float64
+	Bars   []ExplanationBar
+}
+
+type ExplanationBar struct {
+	Label string
+	Text  string
+	Y     int
+	X     float64
+	Width float64
+	Color string
+}
+
+// parseExplain reads the explain option. It is off when empty
+func parseExplain(raw string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+	on, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New("explain must be true or false")
+	}
+	return on, nil
+}
+
+// explain asks c to justify its prediction, returning nil for models
+// that cannot
+func explain(c iris.Classifier, f iris.Features, p iris.Prediction) *iris.Explanation {
+	ex, ok := c.(iris.Explainer)
+	if !ok {
+		return nil
+	}
+	e := ex.Explain(f, p.Species)
+	return &e
+}
+
+func newExplanationChart(e *iris.Explanation) *ExplanationChart {
+	chart := &ExplanationChart{Method: e.Method, Width: explainLabelWidth + explainBarWidth}
+	switch e.Method {
+	case iris.ExplainCoefficients:
+		top := 0.0
+		for _, c := range e.Contributions {
+			top = math.Max(top, math.Abs(c.Contribution))
+		}
+		if top == 0 {
+			top = 1
+		}
+		half := float64(explainBarWidth) / 2
+		chart.ZeroX = explainLabelWidth + half
+		for i, c := range e.Contributions {
+			bar := ExplanationBar{
+				Label: featureOption(c.Feature).Label,
+				Text:  fmt.Sprintf("%s = %g cm contributes %+.2f", c.Feature, c.Value, c.Contribution),
+				Y:     i * explainRowHeight,
+				X:     chart.ZeroX,
+				Width: half * math.Abs(c.Contribution) / top,
+				Color: "#27ae60",
+			}
+			if c.Contribution < 0 {
+				bar.X -= bar.Width
+				bar.Color = "#c0392b"
+			}
+			chart.Bars = append(chart.Bars, bar)
+		}
+	case iris.ExplainNeighbours:
+		top := 0.0
+		for _, n := range e.Neighbours {
+			top = math.Max(top, n.Distance)
+		}
+		if top == 0 {
+			top = 1
+		}
+		chart.ZeroX = explainLabelWidth
+		for i, n := range e.Neighbours {
+			color, ok := speciesColors[n.Species]
+			if !ok {
+				color = "#7f8c8d"
+			}
+			chart.Bars = append(chart.Bars, ExplanationBar{
+				Label: n.Species,
+				Text: fmt.Sprintf("%s %g × %g × %g × %g at distance %.2f",
+					n.Species, n.SepalLength, n.SepalWidth, n.PetalLength, n.PetalWidth, n.Distance),
+				Y:     i * explainRowHeight,
+				X:     chart.ZeroX,
+				Width: explainBarWidth * n.Distance / top,
+				Color: color,
+			})
+		}
+	}
+	chart.Height = explainRowHeight * len(chart.Bars)
+	return chart
+}
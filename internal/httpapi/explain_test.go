@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+func logisticHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	m, err := iris.TrainLogistic(iris.Dataset(), iris.TrainOptions{Epochs: 200, LearningRate: 0.5})
+	if err != nil {
+		t.Fatalf("TrainLogistic() failed: %v", err)
+	}
+	m.Version = "lr"
+	reg, err := iris.NewRegistry([]iris.Entry{iris.EntryFor(m)}, iris.Routing{})
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	return &Handlers{Models: reg}
+}
+
+func predictExplained(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, PredictResponse) {
+	t.Helper()
+	body := `{"sepal_length": 6.3, "sepal_width": 3.3, "petal_length": 6.0, "petal_width": 2.5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/predict?"+query, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.PredictAPI).ServeHTTP(rr, req)
+	var resp PredictResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+	}
+	return rr, resp
+}
+
+func TestPredictAPIExplain(t *testing.T) {
+	_, resp := predictExplained(t, &Handlers{}, "explain=true")
+	if e := resp.Explanation; e == nil || e.Method != iris.ExplainNeighbours || len(e.Neighbours) != iris.DefaultK {
+		t.Errorf("k-NN explanation = %+v", e)
+	}
+
+	_, resp = predictExplained(t, logisticHandlers(t), "explain=true")
+	if e := resp.Explanation; e == nil || e.Method != iris.ExplainCoefficients || len(e.Contributions) != len(iris.FeatureNames) {
+		t.Errorf("logistic explanation = %+v", e)
+	} else if e.Species != resp.Species {
+		t.Errorf("explanation is for %s, prediction is %s", e.Species, resp.Species)
+	}
+
+	rr, resp := predictExplained(t, &Handlers{}, "")
+	if resp.Explanation != nil || strings.Contains(rr.Body.String(), "explanation") {
+		t.Error("explanation returned without being asked for")
+	}
+
+	reg, _ := iris.NewRegistry([]iris.Entry{{ModelInfo: iris.ModelInfo{Version: "fixed"}, Classifier: fixedModel(iris.Setosa)}}, iris.Routing{})
+	_, resp = predictExplained(t, &Handlers{Models: reg}, "explain=true")
+	if resp.Explanation != nil {
+		t.Errorf("model without explanations returned %+v", resp.Explanation)
+	}
+
+	rr, _ = predictExplained(t, &Handlers{}, "explain=maybe")
+	if got, want := rr.Code, http.StatusBadRequest; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+}
+
+func TestPredictPageExplain(t *testing.T) {
+	form := url.Values{
+		"sepal_length": {"6.3"}, "sepal_width": {"3.3"}, "petal_length": {"6.0"}, "petal_width": {"2.5"},
+		"explain": {"true"},
+	}
+	for _, tt := range []struct {
+		name string
+		h    *Handlers
+		want string
+	}{
+		{"knn", &Handlers{}, "nearest flowers"},
+		{"logistic", logisticHandlers(t), "pushed the score"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(tt.h.PredictPage).ServeHTTP(rr, req)
+
+			body := rr.Body.String()
+			for _, want := range []string{tt.want, `aria-label="Explanation"`, "<rect", `name="explain" type="checkbox" value="true" checked`} {
+				if !strings.Contains(body, want) {
+					t.Errorf("page missing %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewExplanationChart(t *testing.T) {
+	chart := newExplanationChart(&iris.Explanation{
+		Method: iris.ExplainCoefficients,
+		Contributions: []iris.Contribution{
+			{Feature: "petal_length", Contribution: 2},
+			{Feature: "sepal_width", Contribution: -1},
+		},
+	})
+	if chart.Height != 2*explainRowHeight || len(chart.Bars) != 2 {
+		t.Fatalf("chart = %+v", chart)
+	}
+	half := float64(explainBarWidth) / 2
+	if b := chart.Bars[0]; b.X != chart.ZeroX || b.Width != half || b.Label != "Petal length" {
+		t.Errorf("positive bar = %+v", b)
+	}
+	if b := chart.Bars[1]; b.X != chart.ZeroX-half/2 || b.Width != half/2 {
+		t.Errorf("negative bar = %+v, want it left of %v", b, chart.ZeroX)
+	}
+}
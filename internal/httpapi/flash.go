@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+)
+
+// Session keys of the Post/Redirect/Get flow
+const (
+	flashesKey      = "flashes"
+	formStateKey    = "form"
+	helloResultsKey = "hello_results"
+)
+
+// maxHelloResults bounds the greeting results kept per session, so that
+// only the most recent result pages can be revisited
+const maxHelloResults = 10
+
+// Flashes is embedded in page data to show flash messages in the layout
+type Flashes []Flash
+
+func (f Flashes) FlashMessages() []Flash { return f }
+
+// formState carries a rejected form submission across the redirect back
+// to the form
+type formState struct {
+	Values map[string]string
+	Errors map[string]string
+}
+
+// helloResult is a greeting shown on its result page
+type helloResult struct {
+	ID   string
+	Name string
+}
+
+// session returns the caller's session when the Post/Redirect/Get flow is
+// available, i.e. h.Sessions is set and the request went through its
+// middleware
+func (h *Handlers) session(r *http.Request) *session.Session {
+	if h.Sessions == nil {
+		return nil
+	}
+	return session.FromContext(r.Context())
+}
+
+// flash queues a message for the next page rendered for s. The caller
+// must save s
+func flash(s *session.Session, kind, message string) {
+	flashes, _ := s.Get(flashesKey).(Flashes)
+	s.Set(flashesKey, append(flashes, Flash{Kind: kind, Message: message}))
+}
+
+// popFlashes returns and forgets the messages queued for r's session
+func (h *Handlers) popFlashes(r *http.Request) Flashes {
+	s := h.session(r)
+	if s == nil {
+		return nil
+	}
+	flashes, _ := s.Get(flashesKey).(Flashes)
+	s.Delete(flashesKey)
+	return flashes
+}
+
+// popFormState returns and forgets the rejected submission of r's session
+func (h *Handlers) popFormState(r *http.Request) (formState, bool) {
+	s := h.session(r)
+	if s == nil {
+		return formState{}, false
+	}
+	state, ok := s.Get(formStateKey).(formState)
+	s.Delete(formStateKey)
+	return state, ok
+}
+
+// addHelloResult remembers name in s under a new unguessable ID and
+// returns the ID. The caller must save s
+func addHelloResult(s *session.Session, name string) string {
+	id := newResultID()
+	results, _ := s.Get(helloResultsKey).([]helloResult)
+	results = append(results, helloResult{ID: id, Name: name})
+	if len(results) > maxHelloResults {
+		results = results[len(results)-maxHelloResults:]
+	}
+	s.Set(helloResultsKey, results)
+	return id
+}
+
+// helloResult looks up a result stored by addHelloResult
+func (h *Handlers) helloResult(r *http.Request, id string) (helloResult, bool) {
+	s := h.session(r)
+	if s == nil {
+		return helloResult{}, false
+	}
+	results, _ := s.Get(helloResultsKey).([]helloResult)
+	for _, res := range results {
+		if res.ID == id {
+			return res, true
+		}
+	}
+	return helloResult{}, false
+}
+
+// PageVariant lets pages be cached per language, except for signed-in
+// users and sessions with flash messages or a rejected form to show, which
+// get personalized pages. It is a pagecache.VaryFunc
+func (h *Handlers) PageVariant(r *http.Request) (string, bool) {
+	if auth.FromContext(r.Context()) != nil {
+		return "", false
+	}
+	if s := h.session(r); s != nil && (s.Get(flashesKey) != nil || s.Get(formStateKey) != nil) {
+		return "", false
+	}
+	return i18n.FromContext(r.Context()).Locale, true
+}
+
+func newResultID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic("httpapi: reading random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
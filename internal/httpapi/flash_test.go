@@ -0,0 +1,170 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/pagecache"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+	"github.com/Elenetta17/iris-web-service/internal/storage"
+)
+
+// browser replays the cookies it receives, like a browser following the
+// Post/Redirect/Get flow
+type browser struct {
+	t       *testing.T
+	handler http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func newPRGBrowser(t *testing.T, h *Handlers) *browser {
+	h.Sessions = session.NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour})
+	cache := pagecache.New(config.PageCacheConfig{MaxBytes: 1 << 20})
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", cache.Handler(pagecache.DefaultCacheControl, h.PageVariant, http.HandlerFunc(h.FormPage)))
+	mux.HandleFunc("POST /hello", h.HelloHandler)
+	mux.HandleFunc("GET /hello/{id}", h.HelloResult)
+	return &browser{t: t, handler: h.Sessions.Middleware(mux), cookies: make(map[string]*http.Cookie)}
+}
+
+func (b *browser) do(method, target string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	rr := httptest.NewRecorder()
+	b.handler.ServeHTTP(rr, req)
+	for _, c := range rr.Result().Cookies() {
+		b.cookies[c.Name] = c
+	}
+	return rr
+}
+
+func TestHelloPostRedirectGet(t *testing.T) {
+	b := newPRGBrowser(t, &Handlers{Store: storage.NewMemoryStore()})
+
+	rr := b.do(http.MethodPost, "/hello", url.Values{"name": {"Alice"}})
+	if got, want := rr.Code, http.StatusSeeOther; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	location := rr.Header().Get("Location")
+	if !strings.HasPrefix(location, "/hello/") {
+		t.Fatalf("Location = %q, want a /hello/{id} result page", location)
+	}
+
+	rr = b.do(http.MethodGet, location, nil)
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"Hello Alice!", `class="flash flash-success"`, "Your greeting has been saved."} {
+		if !strings.Contains(body, want) {
+			t.Errorf("result page missing %q", want)
+		}
+	}
+
+	// Reloading shows the result again, without the flash
+	rr = b.do(http.MethodGet, location, nil)
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("reload status = %d, want %d", got, want)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Hello Alice!") || strings.Contains(body, "has been saved") {
+		t.Errorf("reloaded page should greet again without the flash: %s", body)
+	}
+}
+
+func TestHelloPostRedirectGetInvalidName(t *testing.T) {
+	b := newPRGBrowser(t, &Handlers{})
+	// Cache the form before it has errors to show
+	b.do(http.MethodGet, "/", nil)
+
+	rr := b.do(http.MethodPost, "/hello", url.Values{"name": {"Ada\x07"}})
+	if got, want := rr.Code, http.StatusSeeOther; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if got := rr.Header().Get("Location"); got != "/" {
+		t.Fatalf("Location = %q, want /", got)
+	}
+
+	rr = b.do(http.MethodGet, "/", nil)
+	body := rr.Body.String()
+	for _, want := range []string{`class="flash flash-error"`, "Please correct the errors below.", "invisible control characters", `aria-invalid="true"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("form missing %q", want)
+		}
+	}
+
+	rr = b.do(http.MethodGet, "/", nil)
+	if body := rr.Body.String(); strings.Contains(body, "flash-error") || strings.Contains(body, "aria-invalid") {
+		t.Error("errors shown again after reloading the form")
+	}
+}
+
+func TestHelloResultNotFound(t *testing.T) {
+	b := newPRGBrowser(t, &Handlers{})
+	b.do(http.MethodPost, "/hello", url.Values{"name": {"Alice"}})
+
+	rr := b.do(http.MethodGet, "/hello/0123456789abcdef01234567", nil)
+	if got, want := rr.Code, http.StatusNotFound; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+
+	// Results are kept in the session that posted them
+	other := &browser{t: t, handler: b.handler, cookies: make(map[string]*http.Cookie)}
+	location := b.do(http.MethodPost, "/hello", url.Values{"name": {"Bob"}}).Header().Get("Location")
+	if rr := other.do(http.MethodGet, location, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("other session: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestHelloResultsAreBounded(t *testing.T) {
+	b := newPRGBrowser(t, &Handlers{})
+	first := b.do(http.MethodPost, "/hello", url.Values{"name": {"first"}}).Header().Get("Location")
+	var last string
+	for i := 0; i < maxHelloResults; i++ {
+		last = b.do(http.MethodPost, "/hello", url.Values{"name": {"again"}}).Header().Get("Location")
+	}
+
+	if rr := b.do(http.MethodGet, first, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("oldest result: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := b.do(http.MethodGet, last, nil); rr.Code != http.StatusOK {
+		t.Errorf("newest result: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+func TestPageVariant(t *testing.T) {
+	h := &Handlers{Sessions: session.NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour})}
+	var got []string
+	probe := h.Sessions.Middleware(Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		variant, ok := h.PageVariant(r)
+		if !ok {
+			variant = "bypass"
+		}
+		got = append(got, variant)
+		if r.URL.Query().Has("flash") {
+			flash(session.FromContext(r.Context()), "success", "done")
+		}
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/?lang=fr", nil)
+	probe.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{ID: "ada"}))
+	probe.ServeHTTP(httptest.NewRecorder(), req)
+	probe.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?flash", nil))
+
+	if want := []string{"fr", "bypass", "en"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("variants = %v, want %v", got, want)
+	}
+}
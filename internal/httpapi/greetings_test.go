@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+	"github.com/Elenetta17/iris-web-service/internal/storage"
+)
+
+func TestHelloHandler_SavesGreeting(t *testing.T) {
+	store := storage.NewMemoryStore()
+	h := &Handlers{Store: store}
+
+	form := url.Values{"name": {"Alice"}}
+	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.HelloHandler).ServeHTTP(rr, req)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	greetings, _ := store.List(context.Background(), storage.ListOptions{})
+	if len(greetings) != 1 || greetings[0].Name != "Alice" {
+		t.Errorf("unexpected stored greetings %+v", greetings)
+	}
+}
+
+func TestGreetingsPage(t *testing.T) {
+	store := storage.NewMemoryStore()
+	for i := 1; i <= greetingsPerPage+5; i++ {
+		store.Save(context.Background(), &storage.Greeting{Name: fmt.Sprintf("user-%d", i)})
+	}
+	h := &Handlers{Store: store}
+
+	req := httptest.NewRequest(http.MethodGet, "/greetings", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "Hello user-25!") || strings.Contains(body, "Hello user-5!") {
+		t.Errorf("first page should list the newest greetings only")
+	}
+	if !strings.Contains(body, `href="/greetings?page=2"`) {
+		t.Errorf("first page should link to page 2")
+	}
+	if strings.Contains(body, "/delete") {
+		t.Errorf("delete buttons should be hidden without permission")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/greetings?page=2", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+	body = rr.Body.String()
+	if !strings.Contains(body, "Hello user-5!") || strings.Contains(body, "Hello user-6!") {
+		t.Errorf("second page should list the oldest greetings")
+	}
+
+	// Pages past the end, including ones whose offset would overflow
+	for _, page := range []string{"3", "461168601842738792", "9223372036854775807"} {
+		req = httptest.NewRequest(http.MethodGet, "/greetings?page="+page, nil)
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+		if got, want := rr.Code, http.StatusNotFound; got != want {
+			t.Errorf("page %s: status = %d, want %d", page, got, want)
+		}
+	}
+}
+
+func TestGreetingsPageLocalized(t *testing.T) {
+	store := storage.NewMemoryStore()
+	for i := 1; i <= greetingsPerPage+1; i++ {
+		store.Save(context.Background(), &storage.Greeting{Name: fmt.Sprintf("user-%d", i)})
+	}
+	h := &Handlers{Store: store, Authz: allowList{"admin": {PermGreetingsDelete}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/greetings", nil)
+	ctx := i18n.NewContext(req.Context(), i18n.Lookup("fr"))
+	req = req.WithContext(auth.WithPrincipal(ctx, &auth.Principal{ID: "admin"}))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	for _, want := range []string{"<title>Salutations</title>", "Salutations récentes", "Supprimer cette salutation ?", ">Supprimer</button>", "Plus anciennes"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	for _, english := range []string{"Recent greetings", ">Delete<", "Older"} {
+		if strings.Contains(body, english) {
+			t.Errorf("page contains untranslated %q", english)
+		}
+	}
+}
+
+func TestHelloHandler_StoresUnnamedGreetingUnlocalized(t *testing.T) {
+	store := storage.NewMemoryStore()
+	h := &Handlers{Store: store}
+
+	form := url.Values{"name": {""}}
+	req := httptest.NewRequest(http.MethodPost, "/hello?lang=fr", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Locale(http.HandlerFunc(h.HelloHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	greetings, _ := store.List(context.Background(), storage.ListOptions{})
+	if len(greetings) != 1 || greetings[0].Name != "" {
+		t.Fatalf("stored greetings = %+v, want one without a name", greetings)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/greetings", nil)
+	req = req.WithContext(i18n.NewContext(req.Context(), i18n.Lookup("de")))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+	if body := rr.Body.String(); !strings.Contains(body, "Hallo Welt!") {
+		t.Errorf("greetings page should greet the default name in German")
+	}
+}
+
+func TestGreetingsPage_ShowsDeleteWithPermission(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.Save(context.Background(), &storage.Greeting{Name: "Alice"})
+	h := &Handlers{Store: store, Authz: allowList{"admin": {PermGreetingsDelete}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/greetings", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{ID: "admin"}))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GreetingsPage).ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), `action="/greetings/1/delete"`) {
+		t.Errorf("expected delete button for admin")
+	}
+}
+
+func TestDeleteGreeting(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.Save(context.Background(), &storage.Greeting{Name: "Alice"})
+
+	h := &Handlers{Store: store}
+	rt := NewRouter(allowList{"admin": {PermGreetingsDelete}})
+	rt.HandleFunc("POST /greetings/{id}/delete", h.DeleteGreeting, RequirePermission(PermGreetingsDelete))
+
+	post := func(p *auth.Principal, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := post(&auth.Principal{ID: "user"}, "/greetings/1/delete"); rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := post(&auth.Principal{ID: "admin"}, "/greetings/1/delete"); rr.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusSeeOther)
+	}
+	if n, _ := store.Count(context.Background()); n != 0 {
+		t.Errorf("expected greeting to be deleted, %d left", n)
+	}
+	if rr := post(&auth.Principal{ID: "admin"}, "/greetings/1/delete"); rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
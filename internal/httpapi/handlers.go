@@ -1,49 +1,297 @@
 package httpapi
 
 import (
-	"embed"
-	"html/template"
+	"errors"
 	"log"
+	"net"
 	"net/http"
-	"strings"
+	"strconv"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/monitor"
+	"github.com/Elenetta17/iris-web-service/internal/names"
+	"github.com/Elenetta17/iris-web-service/internal/session"
+	"github.com/Elenetta17/iris-web-service/internal/storage"
 )
 
-//go:embed templates/*.html
-var templateFS embed.FS
+// greetingsPerPage is the page size of the greetings list
+const greetingsPerPage = 20
+
+// maxFormBody bounds the body of the greeting and prediction forms
+const maxFormBody = 4 << 10
 
-var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+// Permissions checked by the handlers
+const (
+	PermGreetingsDelete = "greetings:delete"
+)
+
+// Handlers holds the dependencies shared by the page handlers. The zero
+// value is usable and simply skips the optional collaborators
+type Handlers struct {
+	// Audit records user submissions and admin actions, if set
+	Audit *audit.Logger
+	// Store persists greetings, if set
+	Store storage.GreetingStore
+	// Authz decides which optional page controls are shown
+	Authz Authorizer
+	// Models serves predictions; iris.BundledRegistry() when nil
+	Models *iris.Registry
+	// Monitor records served predictions for drift detection, if set
+	Monitor *monitor.Monitor
+	// Names validates greeting names; the default rules apply when nil
+	Names *names.Validator
+	// Sessions enables Post/Redirect/Get and flash messages for the form.
+	// Without it results are rendered in response to the POST
+	Sessions *session.Manager
+
+	// maxBatchBody overrides defaultMaxBatchBody in tests
+	maxBatchBody int64
+}
+
+type FormData struct {
+	Flashes
+	// User is the signed-in principal, if any
+	User *auth.Principal
+	// Values holds the submitted form fields so they can be shown again
+	Values map[string]string
+	// Errors maps a field name to its validation message
+	Errors map[string]string
+}
 
 type HelloData struct {
+	Flashes
 	Name string
 }
 
-func FormPage(w http.ResponseWriter, r *http.Request) {
+type GreetingsData struct {
+	Flashes
+	Greetings []storage.Greeting
+	Total     int
+	Page      int
+	PrevPage  int
+	NextPage  int
+	CanDelete bool
+}
+
+func (h *Handlers) FormPage(w http.ResponseWriter, r *http.Request) {
 	log.Printf("FormPage called: %s %s", r.Method, r.URL.Path)
-	templates.ExecuteTemplate(w, "form.html", nil)
-	// No error check - if this fails, templates are broken (caught at startup)
+	data := FormData{
+		Flashes: h.popFlashes(r),
+		User:    auth.FromContext(r.Context()),
+	}
+	if state, ok := h.popFormState(r); ok {
+		data.Values, data.Errors = state.Values, state.Errors
+	}
+	Render(w, r, "form.html", data)
 }
 
-func HelloHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) HelloHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("HelloHandler called: %s %s", r.Method, r.URL.Path)
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		w.Header().Set("Allow", http.MethodPost)
+		Error(w, r, http.StatusMethodNotAllowed, "Method not allowed.")
 		return
 	}
 
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBody)
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form", http.StatusBadRequest)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			Error(w, r, http.StatusRequestEntityTooLarge, "The form is too large.")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, "Invalid form.")
+		return
+	}
+
+	name, err := h.Names.Validate(r.PostFormValue("name"))
+	if err != nil {
+		msg := err.Error()
+		var invalid *names.Error
+		if errors.As(err, &invalid) {
+			msg = invalid.Localize(i18n.FromContext(r.Context()))
+		}
+		state := formState{
+			Values: map[string]string{"name": r.PostFormValue("name")},
+			Errors: map[string]string{"name": msg},
+		}
+		if s := h.session(r); s != nil {
+			s.Set(formStateKey, state)
+			flash(s, "error", i18n.FromContext(r.Context()).T("flash.invalid_form"))
+			h.Sessions.Save(w, s)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		data := FormData{
+			User:   auth.FromContext(r.Context()),
+			Values: state.Values,
+			Errors: state.Errors,
+		}
+		RenderStatus(w, r, http.StatusUnprocessableEntity, "form.html", data)
 		return
 	}
+	if h.Store != nil {
+		if err := h.Store.Save(r.Context(), &storage.Greeting{Name: name}); err != nil {
+			log.Printf("saving greeting: %v", err)
+			Error(w, r, http.StatusInternalServerError, i18n.FromContext(r.Context()).T("error.greeting_not_saved"))
+			return
+		}
+	}
+
+	h.record(r, audit.ActionGreeting, map[string]string{"name": name})
 
-	name := strings.TrimSpace(r.PostFormValue("name"))
-	if name == "" {
-		name = "World"
+	if s := h.session(r); s != nil {
+		id := addHelloResult(s, name)
+		if h.Store != nil {
+			flash(s, "success", i18n.FromContext(r.Context()).T("flash.greeting_saved"))
+		}
+		h.Sessions.Save(w, s)
+		http.Redirect(w, r, "/hello/"+id, http.StatusSeeOther)
+		return
 	}
 
 	data := HelloData{
 		Name: name,
 	}
 
-	templates.ExecuteTemplate(w, "hello.html", data)
+	Render(w, r, "hello.html", data)
+}
+
+// HelloResult shows a greeting posted to HelloHandler, so that reloading
+// the page does not submit the form again
+func (h *Handlers) HelloResult(w http.ResponseWriter, r *http.Request) {
+	log.Printf("HelloResult called: %s %s", r.Method, r.URL.Path)
+
+	res, ok := h.helloResult(r, r.PathValue("id"))
+	if !ok {
+		Error(w, r, http.StatusNotFound, "No such greeting.")
+		return
+	}
+
+	data := HelloData{
+		Flashes: h.popFlashes(r),
+		Name:    res.Name,
+	}
+
+	Render(w, r, "hello.html", data)
+}
+
+// GreetingsPage lists the most recent greetings, newest first
+func (h *Handlers) GreetingsPage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("GreetingsPage called: %s %s", r.Method, r.URL.Path)
+
+	if h.Store == nil {
+		Error(w, r, http.StatusNotFound, "Greetings are not stored.")
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	total, err := h.Store.Count(r.Context())
+	if err != nil {
+		log.Printf("counting greetings: %v", err)
+		Error(w, r, http.StatusInternalServerError, "Greetings could not be loaded.")
+		return
+	}
+	// Reject pages past the last one before computing the offset, which
+	// would otherwise overflow for huge page numbers
+	lastPage := max(1, (total+greetingsPerPage-1)/greetingsPerPage)
+	if page > lastPage {
+		Error(w, r, http.StatusNotFound, "No such page.")
+		return
+	}
+	greetings, err := h.Store.List(r.Context(), storage.ListOptions{
+		Limit:  greetingsPerPage,
+		Offset: (page - 1) * greetingsPerPage,
+	})
+	if err != nil {
+		log.Printf("listing greetings: %v", err)
+		Error(w, r, http.StatusInternalServerError, "Greetings could not be loaded.")
+		return
+	}
+
+	data := GreetingsData{
+		Flashes:   h.popFlashes(r),
+		Greetings: greetings,
+		Total:     total,
+		Page:      page,
+		CanDelete: h.Authz != nil && h.Authz.Allowed(auth.FromContext(r.Context()), PermGreetingsDelete),
+	}
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	if page*greetingsPerPage < total {
+		data.NextPage = page + 1
+	}
+
+	Render(w, r, "greetings.html", data)
+}
+
+// DeleteGreeting removes a greeting. The route must require
+// PermGreetingsDelete
+func (h *Handlers) DeleteGreeting(w http.ResponseWriter, r *http.Request) {
+	log.Printf("DeleteGreeting called: %s %s", r.Method, r.URL.Path)
+
+	if h.Store == nil {
+		Error(w, r, http.StatusNotFound, "Greetings are not stored.")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusNotFound, "No such greeting.")
+		return
+	}
+
+	err = h.Store.Delete(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		Error(w, r, http.StatusNotFound, "No such greeting.")
+		return
+	}
+	if err != nil {
+		log.Printf("deleting greeting %d: %v", id, err)
+		Error(w, r, http.StatusInternalServerError, "The greeting could not be deleted.")
+		return
+	}
+
+	h.record(r, audit.ActionGreetingDeleted, map[string]string{"id": strconv.FormatInt(id, 10)})
+	if s := h.session(r); s != nil {
+		flash(s, "success", i18n.FromContext(r.Context()).T("flash.greeting_deleted"))
+		h.Sessions.Save(w, s)
+	}
+	http.Redirect(w, r, "/greetings", http.StatusSeeOther)
+}
+
+// record writes an audit event for r. Audit failures are logged but do not
+// fail the request
+func (h *Handlers) record(r *http.Request, action string, details map[string]string) {
+	err := h.Audit.Record(audit.Event{
+		Action:    action,
+		Actor:     actor(r),
+		RequestID: RequestIDFromContext(r.Context()),
+		Details:   details,
+	})
+	if err != nil {
+		log.Printf("audit: recording %s: %v", action, err)
+	}
+}
+
+// actor identifies who made the request: the principal when authenticated,
+// the client IP otherwise
+func actor(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Method + ":" + p.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
 }
@@ -1,12 +1,18 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/config"
 )
 
 func runHelloRequest(t *testing.T, method string, form url.Values, contentType string) *httptest.ResponseRecorder {
@@ -23,14 +29,14 @@ func runHelloRequest(t *testing.T, method string, form url.Values, contentType s
 		req.Header.Set("Content-Type", contentType)
 	}
 	rr := httptest.NewRecorder()
-	http.HandlerFunc(HelloHandler).ServeHTTP(rr, req)
+	http.HandlerFunc((&Handlers{}).HelloHandler).ServeHTTP(rr, req)
 	return rr
 }
 
 func TestFormPage(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	rr := httptest.NewRecorder()
-	http.HandlerFunc(FormPage).ServeHTTP(rr, req)
+	http.HandlerFunc((&Handlers{}).FormPage).ServeHTTP(rr, req)
 
 	if got, want := rr.Code, http.StatusOK; got != want {
 		t.Fatalf("status = %d, want %d", got, want)
@@ -120,16 +126,31 @@ func TestHelloHandler_MethodNotAllowed(t *testing.T) {
 			if !strings.Contains(rr.Body.String(), "Method not allowed") {
 				t.Errorf("body %q does not contain %q", rr.Body.String(), "Method not allowed")
 			}
+			if got := rr.Header().Get("Allow"); got != http.MethodPost {
+				t.Errorf("Allow = %q, want %q", got, http.MethodPost)
+			}
 		})
 	}
 }
 
+func TestHelloHandler_FormTooLarge(t *testing.T) {
+	form := url.Values{"name": {strings.Repeat("a", maxFormBody)}}
+	rr := runHelloRequest(t, http.MethodPost, form, "application/x-www-form-urlencoded")
+
+	if got, want := rr.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if !strings.Contains(rr.Body.String(), "413 Request too large") {
+		t.Errorf("413 error page not rendered: %s", rr.Body.String())
+	}
+}
+
 func TestHelloHandler_InvalidForm(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("invalid"))
 	req.Header.Set("Content-Type", "multipart/form-data; boundary=")
 
 	rr := httptest.NewRecorder()
-	http.HandlerFunc(HelloHandler).ServeHTTP(rr, req)
+	http.HandlerFunc((&Handlers{}).HelloHandler).ServeHTTP(rr, req)
 
 	if got, want := rr.Code, http.StatusBadRequest; got != want {
 		t.Fatalf("status = %d, want %d", got, want)
@@ -186,3 +207,80 @@ func TestHelloHandler_XSSProtection(t *testing.T) {
 		t.Error("body does not contain escaped script tag")
 	}
 }
+
+func TestHelloHandler_InvalidName(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{"control character", "Ada\x07", "invisible control characters"},
+		{"bidi override", "Ada\u202eecalevol", "invisible control characters"},
+		{"too long", strings.Repeat("é", 65), "at most 64 characters"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			form := url.Values{"name": {tc.input}}
+			rr := runHelloRequest(t, http.MethodPost, form, "application/x-www-form-urlencoded")
+
+			if got, want := rr.Code, http.StatusUnprocessableEntity; got != want {
+				t.Fatalf("status = %d, want %d", got, want)
+			}
+			body := rr.Body.String()
+			for _, want := range []string{`action="/hello"`, tc.errMsg, `aria-invalid="true"`} {
+				if !strings.Contains(body, want) {
+					t.Errorf("body missing %q", want)
+				}
+			}
+			if strings.Contains(body, "Hello") && strings.Contains(body, "<h1>") {
+				t.Error("invalid name was greeted")
+			}
+		})
+	}
+}
+
+func TestHelloHandler_NormalizesName(t *testing.T) {
+	// "e" followed by a combining acute accent
+	form := url.Values{"name": {"Jose\u0301"}}
+	rr := runHelloRequest(t, http.MethodPost, form, "application/x-www-form-urlencoded")
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Hello Jos\u00e9!") {
+		t.Errorf("body does not contain the composed name: %s", body)
+	}
+}
+
+func TestHelloHandler_RecordsAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(config.AuditConfig{Path: path})
+	if err != nil {
+		t.Fatalf("audit.Open() failed: %v", err)
+	}
+	h := &Handlers{Audit: auditLog}
+
+	form := url.Values{"name": {"Alice"}}
+	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.10:5555"
+	rr := httptest.NewRecorder()
+	RequestID(http.HandlerFunc(h.HelloHandler)).ServeHTTP(rr, req)
+	auditLog.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	var event audit.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("invalid audit record %q: %v", data, err)
+	}
+	if event.Action != audit.ActionGreeting || event.Actor != "ip:192.0.2.10" || event.Details["name"] != "Alice" {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+	if event.RequestID != rr.Header().Get(RequestIDHeader) {
+		t.Errorf("audit request_id = %q, want %q", event.RequestID, rr.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+// LocaleCookie remembers the language chosen with ?lang=
+const LocaleCookie = "lang"
+
+// localeCookieMaxAge keeps the chosen language for a year
+const localeCookieMaxAge = 365 * 24 * time.Hour
+
+// Locale selects the catalog pages are rendered in. A supported ?lang=
+// query parameter wins and is remembered in LocaleCookie; otherwise the
+// cookie, then Accept-Language decide
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c *i18n.Catalog
+		if lang := r.URL.Query().Get("lang"); lang != "" {
+			if c = i18n.Lookup(lang); c != nil {
+				http.SetCookie(w, &http.Cookie{
+					Name:     LocaleCookie,
+					Value:    c.Locale,
+					Path:     "/",
+					MaxAge:   int(localeCookieMaxAge.Seconds()),
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+		}
+		if c == nil {
+			if cookie, err := r.Cookie(LocaleCookie); err == nil {
+				c = i18n.Lookup(cookie.Value)
+			}
+		}
+		if c == nil {
+			c = i18n.Negotiate(r.Header.Get("Accept-Language"))
+		}
+
+		w.Header().Set("Content-Language", c.Locale)
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Add("Vary", "Cookie")
+		next.ServeHTTP(w, r.WithContext(i18n.NewContext(r.Context(), c)))
+	})
+}
@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		cookie     string
+		accept     string
+		want       string
+		wantCookie string
+	}{
+		{"default", "", "", "", "en", ""},
+		{"accept language", "", "", "de-DE,de;q=0.9", "de", ""},
+		{"cookie beats header", "", "fr", "de", "fr", ""},
+		{"query beats cookie", "?lang=de", "fr", "", "de", "de"},
+		{"unsupported query ignored", "?lang=xx", "fr", "", "fr", ""},
+		{"unsupported cookie ignored", "", "xx", "de", "de", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen string
+			h := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = i18n.FromContext(r.Context()).Locale
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.query, nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: LocaleCookie, Value: tc.cookie})
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept-Language", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if seen != tc.want {
+				t.Errorf("locale = %q, want %q", seen, tc.want)
+			}
+			if got := rr.Header().Get("Content-Language"); got != tc.want {
+				t.Errorf("Content-Language = %q, want %q", got, tc.want)
+			}
+			var got string
+			for _, c := range rr.Result().Cookies() {
+				if c.Name == LocaleCookie {
+					got = c.Value
+				}
+			}
+			if got != tc.wantCookie {
+				t.Errorf("cookie = %q, want %q", got, tc.wantCookie)
+			}
+		})
+	}
+}
+
+func TestLocalizedPages(t *testing.T) {
+	tests := []struct {
+		lang  string
+		name  string
+		wants []string
+	}{
+		{"en", "Alice", []string{`<html lang="en">`, "Hello Alice!"}},
+		{"fr", "Alice", []string{`<html lang="fr">`, "Bonjour Alice !", "<title>Bonjour</title>"}},
+		{"de", "Alice", []string{`<html lang="de">`, "Hallo Alice!", ">Datensatz</a>"}},
+		{"fr", "", []string{"Bonjour le monde !"}},
+		{"de", "", []string{"Hallo Welt!"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.lang+"/"+tc.name, func(t *testing.T) {
+			form := url.Values{"name": {tc.name}}
+			req := httptest.NewRequest(http.MethodPost, "/hello?lang="+tc.lang, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			Locale(http.HandlerFunc((&Handlers{}).HelloHandler)).ServeHTTP(rr, req)
+
+			if got, want := rr.Code, http.StatusOK; got != want {
+				t.Fatalf("status = %d, want %d", got, want)
+			}
+			body := rr.Body.String()
+			for _, want := range tc.wants {
+				if !strings.Contains(body, want) {
+					t.Errorf("body missing %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormPageLocalized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rr := httptest.NewRecorder()
+	Locale(http.HandlerFunc((&Handlers{}).FormPage)).ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	for _, want := range []string{`placeholder="Votre nom"`, ">Dire bonjour</button>", ">Identifier un iris</a>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+}
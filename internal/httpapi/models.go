@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+// PermModelsManage allows promoting models and changing the traffic split
+const PermModelsManage = "models:manage"
+
+// ModelListing is one entry of the ListModels response
+type ModelListing struct {
+	iris.ModelInfo
+	// Traffic is the share of predictions currently served by the model
+	Traffic float64 `json:"traffic"`
+}
+
+// ModelsResponse is the body returned by ListModels and the admin
+// endpoints
+type ModelsResponse struct {
+	Routing iris.Routing   `json:"routing"`
+	Models  []ModelListing `json:"models"`
+}
+
+func (h *Handlers) modelsResponse() ModelsResponse {
+	reg := h.models()
+	routing := reg.Routing()
+	resp := ModelsResponse{Routing: routing}
+	for _, info := range reg.List() {
+		l := ModelListing{ModelInfo: info}
+		switch info.Version {
+		case routing.Primary:
+			l.Traffic = 1 - routing.CandidateWeight
+		case routing.Candidate:
+			l.Traffic = routing.CandidateWeight
+		}
+		resp.Models = append(resp.Models, l)
+	}
+	return resp
+}
+
+// ListModels returns the registered models and the current routing
+func (h *Handlers) ListModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.modelsResponse())
+}
+
+// PromoteModel makes the model named by the {version} path value the
+// primary. Artifacts added to the model directory since startup are picked
+// up first. The route must require PermModelsManage
+func (h *Handlers) PromoteModel(w http.ResponseWriter, r *http.Request) {
+	version := r.PathValue("version")
+	reg := h.models()
+
+	err := reg.Promote(version)
+	if errors.Is(err, iris.ErrUnknownVersion) {
+		if reloadErr := reg.Reload(); reloadErr != nil {
+			log.Printf("reloading models: %v", reloadErr)
+		} else {
+			err = reg.Promote(version)
+		}
+	}
+	if errors.Is(err, iris.ErrUnknownVersion) {
+		Error(w, r, http.StatusNotFound, "No such model version.")
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusConflict, err.Error())
+		return
+	}
+
+	log.Printf("models: promoted %s", version)
+	h.record(r, audit.ActionModelPromoted, map[string]string{"version": version})
+	writeJSON(w, http.StatusOK, h.modelsResponse())
+}
+
+// SetModelRouting replaces the traffic split with the iris.Routing in the
+// JSON body. The route must require PermModelsManage
+func (h *Handlers) SetModelRouting(w http.ResponseWriter, r *http.Request) {
+	var routing iris.Routing
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPredictBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&routing); err != nil {
+		Error(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	reg := h.models()
+	err := reg.SetRouting(routing)
+	if errors.Is(err, iris.ErrUnknownVersion) {
+		if reg.Reload() == nil {
+			err = reg.SetRouting(routing)
+		}
+	}
+	if err != nil {
+		Error(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	log.Printf("models: routing set to %+v", routing)
+	h.record(r, audit.ActionModelRouting, map[string]string{
+		"primary":          routing.Primary,
+		"candidate":        routing.Candidate,
+		"candidate_weight": strconv.FormatFloat(routing.CandidateWeight, 'f', -1, 64),
+	})
+	writeJSON(w, http.StatusOK, h.modelsResponse())
+}
@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+func newModelsRouter(t *testing.T, h *Handlers) *Router {
+	t.Helper()
+	rt := NewRouter(allowList{"admin": {PermModelsManage}})
+	rt.HandleFunc("GET /api/v1/models", h.ListModels)
+	rt.HandleFunc("POST /api/v1/models/{version}/promote", h.PromoteModel, RequirePermission(PermModelsManage))
+	rt.HandleFunc("PUT /api/v1/models/routing", h.SetModelRouting, RequirePermission(PermModelsManage))
+	return rt
+}
+
+func testRegistry(t *testing.T) *iris.Registry {
+	t.Helper()
+	a := iris.Entry{ModelInfo: iris.ModelInfo{Version: "a", Accuracy: 0.9}, Classifier: fixedModel(iris.Setosa)}
+	b := iris.Entry{ModelInfo: iris.ModelInfo{Version: "b", Accuracy: 0.95}, Classifier: fixedModel(iris.Virginica)}
+	reg, err := iris.NewRegistry([]iris.Entry{a, b}, iris.Routing{Primary: "a", Candidate: "b", CandidateWeight: 0.2})
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	return reg
+}
+
+func sendAs(h http.Handler, p *auth.Principal, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if p != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestListModels(t *testing.T) {
+	rt := newModelsRouter(t, &Handlers{Models: testRegistry(t)})
+	rr := sendAs(rt, &auth.Principal{ID: "user"}, http.MethodGet, "/api/v1/models", "")
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	var resp ModelsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.Routing.Primary != "a" || len(resp.Models) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	traffic := map[string]float64{}
+	for _, m := range resp.Models {
+		traffic[m.Version] = m.Traffic
+	}
+	if traffic["a"] != 0.8 || traffic["b"] != 0.2 {
+		t.Errorf("traffic = %v, want a=0.8 b=0.2", traffic)
+	}
+}
+
+func TestPromoteModel(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := audit.Open(config.AuditConfig{Path: logPath})
+	if err != nil {
+		t.Fatalf("audit.Open() failed: %v", err)
+	}
+	defer logger.Close()
+
+	reg := testRegistry(t)
+	rt := newModelsRouter(t, &Handlers{Models: reg, Audit: logger})
+	admin := &auth.Principal{ID: "admin", Method: auth.MethodAPIKey}
+
+	if rr := sendAs(rt, &auth.Principal{ID: "user"}, http.MethodPost, "/api/v1/models/b/promote", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("non-admin promote status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := sendAs(rt, admin, http.MethodPost, "/api/v1/models/zzz/promote", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown version status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr := sendAs(rt, admin, http.MethodPost, "/api/v1/models/b/promote", "")
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d: %s", got, want, rr.Body)
+	}
+	if got := reg.Routing(); got != (iris.Routing{Primary: "b"}) {
+		t.Errorf("routing after promote = %+v", got)
+	}
+
+	data, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(data), audit.ActionModelPromoted) || !strings.Contains(string(data), `"version":"b"`) {
+		t.Errorf("promotion not audited: %s", data)
+	}
+}
+
+func TestSetModelRouting(t *testing.T) {
+	reg := testRegistry(t)
+	rt := newModelsRouter(t, &Handlers{Models: reg})
+	admin := &auth.Principal{ID: "admin"}
+
+	rr := sendAs(rt, admin, http.MethodPut, "/api/v1/models/routing", `{"primary":"b","candidate":"a","candidate_weight":0.5}`)
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d: %s", got, want, rr.Body)
+	}
+	if got := reg.Routing(); got.Primary != "b" || got.CandidateWeight != 0.5 {
+		t.Errorf("routing = %+v", got)
+	}
+
+	for _, body := range []string{`{"primary":"b","candidate_weight":2}`, `{"primary":"x"}`} {
+		if rr := sendAs(rt, admin, http.MethodPut, "/api/v1/models/routing", body); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("body %s: status = %d, want %d", body, rr.Code, http.StatusUnprocessableEntity)
+		}
+	}
+	if rr := sendAs(rt, admin, http.MethodPut, "/api/v1/models/routing", `{"primary":`); rr.Code != http.StatusBadRequest {
+		t.Errorf("malformed body status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,179 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+// maxPredictBody bounds the JSON body of a single prediction request
+const maxPredictBody = 1 << 10
+
+// PredictData is rendered by predict.html
+type PredictData struct {
+	// Values holds the submitted form fields so they can be shown again
+	Values map[string]string
+	// Errors maps a field name to its validation message
+	Errors map[string]string
+	// Explain asks for the result to be explained
+	Explain bool
+	Result  *PredictResult
+}
+
+// PredictResult is a prediction with its probabilities in display order
+type PredictResult struct {
+	Species       string
+	Probabilities []ClassProbability
+	ModelVersion  string
+	// Explanation is set when requested and supported by the model
+	Explanation *ExplanationChart
+}
+
+type ClassProbability struct {
+	Species string
+	Percent float64
+}
+
+// ModelVersionHeader reports which model served a prediction
+const ModelVersionHeader = "X-Model-Version"
+
+// PredictResponse is the body returned by PredictAPI
+type PredictResponse struct {
+	iris.Prediction
+	ModelVersion string            `json:"model_version"`
+	Explanation  *iris.Explanation `json:"explanation,omitempty"`
+}
+
+func (h *Handlers) models() *iris.Registry {
+	if h.Models != nil {
+		return h.Models
+	}
+	return iris.BundledRegistry()
+}
+
+// model selects the model serving one request and reports its version in
+// the response headers
+func (h *Handlers) model(w http.ResponseWriter) *iris.Entry {
+	e := h.models().Select()
+	w.Header().Set(ModelVersionHeader, e.Version)
+	return e
+}
+
+// PredictPage shows the prediction form and, on POST, the predicted species
+func (h *Handlers) PredictPage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("PredictPage called: %s %s", r.Method, r.URL.Path)
+
+	data := PredictData{Values: make(map[string]string), Errors: make(map[string]string)}
+	if r.Method != http.MethodPost {
+		Render(w, r, "predict.html", data)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBody)
+	if err := r.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			Error(w, r, http.StatusRequestEntityTooLarge, "The form is too large.")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, "Invalid form.")
+		return
+	}
+
+	var err error
+	if data.Explain, err = parseExplain(r.PostFormValue("explain")); err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var v [4]float64
+	for i, name := range iris.FeatureNames {
+		raw := strings.TrimSpace(r.PostFormValue(name))
+		data.Values[name] = raw
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			data.Errors[name] = "must be a number"
+			continue
+		}
+		v[i] = f
+	}
+	features := iris.Features{SepalLength: v[0], SepalWidth: v[1], PetalLength: v[2], PetalWidth: v[3]}
+
+	var verr iris.ValidationError
+	if err := features.Validate(); errors.As(err, &verr) {
+		for _, fe := range verr {
+			if _, seen := data.Errors[fe.Field]; !seen {
+				data.Errors[fe.Field] = fe.Message
+			}
+		}
+	}
+	if len(data.Errors) > 0 {
+		RenderStatus(w, r, http.StatusUnprocessableEntity, "predict.html", data)
+		return
+	}
+
+	model := h.model(w)
+	p := model.Classifier.Predict(features)
+	h.observe(model, features, p)
+	data.Result = newPredictResult(p, model.Version)
+	if data.Explain {
+		if e := explain(model.Classifier, features, p); e != nil {
+			data.Result.Explanation = newExplanationChart(e)
+		}
+	}
+	Render(w, r, "predict.html", data)
+}
+
+func newPredictResult(p iris.Prediction, version string) *PredictResult {
+	res := &PredictResult{Species: p.Species, ModelVersion: version}
+	for _, s := range iris.Species {
+		res.Probabilities = append(res.Probabilities, ClassProbability{Species: s, Percent: 100 * p.Probabilities[s]})
+	}
+	sort.SliceStable(res.Probabilities, func(i, j int) bool {
+		return res.Probabilities[i].Percent > res.Probabilities[j].Percent
+	})
+	return res
+}
+
+// PredictAPI classifies the flower described by a JSON body of the form
+// {"sepal_length": 5.1, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}.
+// With ?explain=true the response also says why, if the model can
+func (h *Handlers) PredictAPI(w http.ResponseWriter, r *http.Request) {
+	wantExplanation, err := parseExplain(r.URL.Query().Get("explain"))
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var features iris.Features
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPredictBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&features); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			Error(w, r, http.StatusRequestEntityTooLarge, "Request body too large.")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := features.Validate(); err != nil {
+		Error(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	model := h.model(w)
+	p := model.Classifier.Predict(features)
+	h.observe(model, features, p)
+	resp := PredictResponse{Prediction: p, ModelVersion: model.Version}
+	if wantExplanation {
+		resp.Explanation = explain(model.Classifier, features, p)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+)
+
+func TestPredictAPI(t *testing.T) {
+	body := `{"sepal_length": 6.3, "sepal_width": 3.3, "petal_length": 6.0, "petal_width": 2.5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/predict", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc((&Handlers{}).PredictAPI).ServeHTTP(rr, req)
+
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d: %s", got, want, rr.Body)
+	}
+	var p PredictResponse
+	if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if p.Species != iris.Virginica || p.Probabilities[iris.Virginica] != 1 {
+		t.Errorf("unexpected prediction %+v", p)
+	}
+	if p.ModelVersion != iris.BundledVersion {
+		t.Errorf("model_version = %q, want %q", p.ModelVersion, iris.BundledVersion)
+	}
+}
+
+func TestPredictAPI_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"malformed", `{"sepal_length":`, http.StatusBadRequest},
+		{"unknown field", `{"sepal_length": 5, "colour": "blue"}`, http.StatusBadRequest},
+		{"out of range", `{"sepal_length": 51, "sepal_width": 3.5, "petal_length": 1.4, "petal_width": 0.2}`, http.StatusUnprocessableEntity},
+		{"missing fields", `{"sepal_length": 5.1}`, http.StatusUnprocessableEntity},
+		{"too large", `{"sepal_length": 5.1` + strings.Repeat(" ", maxPredictBody) + `}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/predict", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc((&Handlers{}).PredictAPI).ServeHTTP(rr, req)
+
+			if got := rr.Code; got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+			if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("content-type = %q, want application/problem+json", got)
+			}
+		})
+	}
+}
+
+func TestPredictAPI_UsesConfiguredModel(t *testing.T) {
+	models, err := iris.NewRegistry([]iris.Entry{{
+		ModelInfo:  iris.ModelInfo{Version: "fixed"},
+		Classifier: fixedModel(iris.Setosa),
+	}}, iris.Routing{})
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	h := &Handlers{Models: models}
+	body := `{"sepal_length": 6.3, "sepal_width": 3.3, "petal_length": 6.0, "petal_width": 2.5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/predict", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.PredictAPI).ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), `"species":"setosa"`) {
+		t.Errorf("configured model not used: %s", rr.Body)
+	}
+	if got := rr.Header().Get(ModelVersionHeader); got != "fixed" {
+		t.Errorf("%s = %q, want fixed", ModelVersionHeader, got)
+	}
+}
+
+type fixedModel string
+
+func (m fixedModel) Predict(iris.Features) iris.Prediction {
+	return iris.Prediction{Species: string(m), Probabilities: map[string]float64{string(m): 1}}
+}
+
+func postPredictForm(t *testing.T, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc((&Handlers{}).PredictPage).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPredictPage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/predict", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc((&Handlers{}).PredictPage).ServeHTTP(rr, req)
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	for _, name := range iris.FeatureNames {
+		if !strings.Contains(rr.Body.String(), `name="`+name+`"`) {
+			t.Errorf("form is missing the %s field", name)
+		}
+	}
+
+	rr = postPredictForm(t, url.Values{
+		"sepal_length": {"5.1"}, "sepal_width": {"3.5"}, "petal_length": {"1.4"}, "petal_width": {"0.2"},
+	})
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "Iris setosa") || !strings.Contains(body, "100%") {
+		t.Errorf("result missing from page: %s", body)
+	}
+	if !strings.Contains(body, `value="5.1"`) {
+		t.Error("submitted values not kept in the form")
+	}
+}
+
+func TestPredictPage_Invalid(t *testing.T) {
+	rr := postPredictForm(t, url.Values{
+		"sepal_length": {"abc"}, "sepal_width": {"3.5"}, "petal_length": {"99"}, "petal_width": {"0.2"},
+	})
+	if got, want := rr.Code, http.StatusUnprocessableEntity; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "Sepal length must be a number") {
+		t.Error("missing parse error message")
+	}
+	if !strings.Contains(body, "Petal length must be greater than") {
+		t.Error("missing range error message")
+	}
+	if strings.Contains(body, "<h2>") {
+		t.Error("result rendered for invalid input")
+	}
+}
+
+func TestPredictPage_FormTooLarge(t *testing.T) {
+	rr := postPredictForm(t, url.Values{
+		"sepal_length": {"5.1"}, "sepal_width": {"3.5"}, "petal_length": {"1.4"}, "petal_width": {"0.2"},
+		"padding": {strings.Repeat("a", maxFormBody)},
+	})
+	if got, want := rr.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if !strings.Contains(rr.Body.String(), "413 Request too large") {
+		t.Errorf("413 error page not rendered: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panicking handler into the 500 error page. The panic is
+// logged with its stack and the request ID. If the handler had already
+// started its response, the connection is aborted instead so that the
+// client cannot mistake the partial response for a complete one
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		outer := w.Header().Clone()
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			log.Printf("panic serving %s %s: request_id=%s: %v\n%s",
+				r.Method, r.URL.Path, RequestIDFromContext(r.Context()), v, debug.Stack())
+			if tw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			// Drop whatever the handler set for its own response, such as
+			// its Content-Type or caching headers, but keep those set by
+			// the middleware in front of Recover
+			h := w.Header()
+			for k := range h {
+				delete(h, k)
+			}
+			for k, v := range outer {
+				h[k] = v
+			}
+			Error(w, r, http.StatusInternalServerError, "")
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// trackingWriter records whether the response has been started
+type trackingWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *trackingWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
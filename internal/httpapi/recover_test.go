@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	RequestID(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got, want := rr.Code, http.StatusInternalServerError; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "500 Something went wrong") {
+		t.Errorf("error page not rendered: %s", body)
+	}
+	if id := rr.Header().Get(RequestIDHeader); !strings.Contains(body, id) {
+		t.Errorf("expected request ID %q in page", id)
+	}
+}
+
+func TestRecoverDropsHandlerHeaders(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		panic("boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	RequestID(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want the error page's", ct)
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc == "max-age=3600" {
+		t.Errorf("error page kept the handler's Cache-Control")
+	}
+	if rr.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("request ID header set in front of Recover was dropped")
+	}
+}
+
+func TestRecoverAfterWriting(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	}))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoverPassesThrough(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rr.Code, http.StatusTeapot; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package httpapi
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+	"github.com/Elenetta17/iris-web-service/internal/metrics"
+)
+
+// Flash is a one-time notice shown above the page content
+type Flash struct {
+	// Kind is "success" or "error"
+	Kind    string
+	Message string
+}
+
+// flashCarrier is implemented by page data with flash messages to show
+type flashCarrier interface {
+	FlashMessages() []Flash
+}
+
+// maxPooledBuffer keeps unusually large pages from pinning memory in the
+// buffer pool
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// RenderFailures counts pages that failed to render
+var RenderFailures = metrics.NewCounter("iris_template_render_failures_total",
+	"Pages that failed to render, by page.", "page")
+
+// Render writes page inside the base layout with status 200
+func Render(w http.ResponseWriter, r *http.Request, page string, data any) error {
+	return RenderStatus(w, r, http.StatusOK, page, data)
+}
+
+// RenderStatus renders page into a buffer and writes it with status only
+// if rendering succeeded. On failure the client gets the 500 error page
+// instead of half a page; the failure is logged with the request ID and
+// counted in RenderFailures, so handlers may ignore the returned error
+func RenderStatus(w http.ResponseWriter, r *http.Request, status int, page string, data any) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+
+	pages, err := templates.Load().PagesIn(i18n.FromContext(r.Context()).Locale)
+	if err != nil {
+		// Only reloading loaders get here: show the parse error to the
+		// developer rather than a generic error page
+		RenderFailures.Inc(page)
+		log.Printf("loading templates: %v", err)
+		writeTemplateError(w, err)
+		return err
+	}
+
+	err = execute(buf, r, pages, page, data)
+	if err != nil {
+		RenderFailures.Inc(page)
+		requestID := RequestIDFromContext(r.Context())
+		log.Printf("rendering %s failed: request_id=%s: %v", page, requestID, err)
+
+		buf.Reset()
+		status = http.StatusInternalServerError
+		if page == "error.html" || execute(buf, r, pages, "error.html", newErrorData(r, status, "")) != nil {
+			http.Error(w, http.StatusText(status), status)
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	if _, werr := buf.WriteTo(w); werr != nil {
+		log.Printf("writing %s: %v", page, werr)
+	}
+	return err
+}
+
+func execute(buf *bytes.Buffer, r *http.Request, pages map[string]*template.Template, page string, data any) error {
+	t, ok := pages[page]
+	if !ok {
+		return fmt.Errorf("unknown page %q", page)
+	}
+	return t.ExecuteTemplate(buf, "base", layoutData{Page: data, url: r.URL})
+}
+
+// layoutData is executed by the base layout. The page's blocks receive
+// Page; the header also needs the request URL for the language switcher
+type layoutData struct {
+	Page any
+	url  *url.URL
+}
+
+// LangURL links to the current page in locale, keeping the other query
+// parameters
+func (d layoutData) LangURL(locale string) string {
+	q := url.Values{}
+	if d.url != nil {
+		q = d.url.Query()
+	}
+	q.Set("lang", locale)
+	return "?" + q.Encode()
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type flashPage struct {
+	Name    string
+	flashes []Flash
+}
+
+func (p flashPage) FlashMessages() []Flash { return p.flashes }
+
+func TestRenderUsesBaseLayout(t *testing.T) {
+	tests := []struct {
+		page  string
+		data  any
+		title string
+	}{
+		{"form.html", FormData{}, "<title>Form</title>"},
+		{"hello.html", HelloData{Name: "Ada"}, "<title>Hello</title>"},
+		{"error.html", ErrorData{Status: 404, Title: "Not Found"}, "<title>404 Not Found</title>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			if err := Render(rr, httptest.NewRequest("GET", "/", nil), tt.page, tt.data); err != nil {
+				t.Fatalf("Render() failed: %v", err)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			body := rr.Body.String()
+			for _, want := range []string{"<!DOCTYPE html>", tt.title, `<nav>`, "<footer>"} {
+				if !strings.Contains(body, want) {
+					t.Errorf("page missing %q", want)
+				}
+			}
+			if n := strings.Count(body, "<title>"); n != 1 {
+				t.Errorf("page has %d titles, want 1", n)
+			}
+		})
+	}
+}
+
+func TestParsePagesKeepsBlocksApart(t *testing.T) {
+	set, err := parsePages(fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`{{define "base"}}[{{block "title" .}}{{end}}]{{template "footer" .}}{{end}}`)},
+		"partials/footer.html": {Data: []byte(`{{define "footer"}}.{{end}}`)},
+		"pages/a.html":         {Data: []byte(`{{define "title"}}A{{end}}`)},
+		"pages/b.html":         {Data: []byte(`{{define "title"}}B{{end}}`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("parsePages() failed: %v", err)
+	}
+	for page, want := range map[string]string{"a.html": "[A].", "b.html": "[B]."} {
+		var b strings.Builder
+		if err := set[page].ExecuteTemplate(&b, "base", nil); err != nil {
+			t.Fatalf("%s: %v", page, err)
+		}
+		if b.String() != want {
+			t.Errorf("%s = %q, want %q", page, b.String(), want)
+		}
+	}
+}
+
+func TestRenderFlashes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data := flashPage{Name: "Ada", flashes: []Flash{{Kind: "success", Message: "Saved <b>it</b>"}}}
+	if err := Render(rr, httptest.NewRequest("GET", "/", nil), "hello.html", data); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, `class="flash flash-success"`) || !strings.Contains(body, "Saved &lt;b&gt;it&lt;/b&gt;") {
+		t.Errorf("flash not rendered or not escaped: %s", body)
+	}
+	if !strings.Contains(body, "Hello Ada!") {
+		t.Error("page content missing")
+	}
+}
+
+func TestRenderSetsContentLength(t *testing.T) {
+	rr := httptest.NewRecorder()
+	RenderStatus(rr, httptest.NewRequest("GET", "/", nil), http.StatusTeapot, "hello.html", HelloData{Name: "Ada"})
+	if got, want := rr.Code, http.StatusTeapot; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if got, want := rr.Header().Get("Content-Length"), strconv.Itoa(rr.Body.Len()); got != want {
+		t.Errorf("Content-Length = %s, want %s", got, want)
+	}
+}
+
+func TestRenderFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		data any
+	}{
+		// hello.html fails after the layout has started writing
+		{"execution error", "hello.html", struct{}{}},
+		{"unknown page", "missing.html", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := RenderFailures.Value(tt.page)
+			req := httptest.NewRequest("GET", "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "req-42"))
+			rr := httptest.NewRecorder()
+
+			if err := Render(rr, req, tt.page, tt.data); err == nil {
+				t.Fatal("expected error")
+			}
+			if got, want := rr.Code, http.StatusInternalServerError; got != want {
+				t.Errorf("status = %d, want %d", got, want)
+			}
+			body := rr.Body.String()
+			if !strings.Contains(body, "500 Something went wrong") || !strings.Contains(body, "req-42") {
+				t.Errorf("error page not rendered: %s", body)
+			}
+			if strings.Contains(body, "Hello") {
+				t.Error("partial page was written")
+			}
+			if got := RenderFailures.Value(tt.page) - before; got != 1 {
+				t.Errorf("failures counted = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRenderErrorPageFailure(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if err := Render(rr, httptest.NewRequest("GET", "/", nil), "error.html", struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if got, want := rr.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want plain text fallback", ct)
+	}
+}
+
+func TestRenderLanguageLinksKeepQuery(t *testing.T) {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/greetings?page=2&lang=de", nil)
+	if err := Render(rr, req, "form.html", FormData{}); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{`href="?lang=fr&amp;page=2"`, `href="?lang=en&amp;page=2"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing language link %q", want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"propagated", "abc-123.DEF_4", true},
+		{"rejected", "bad id\nwith newline", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("response ID %q does not match context ID %q", got, seen)
+			}
+			if tc.keep && got != tc.incoming {
+				t.Errorf("expected incoming ID %q to be kept, got %q", tc.incoming, got)
+			}
+			if !tc.keep && got == tc.incoming {
+				t.Errorf("expected incoming ID %q to be replaced", tc.incoming)
+			}
+		})
+	}
+}
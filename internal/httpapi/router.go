@@ -0,0 +1,228 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/pagecache"
+)
+
+// Authorizer decides whether a principal holds a permission
+type Authorizer interface {
+	Allowed(p *auth.Principal, permission string) bool
+}
+
+// Route describes a registered handler and the permission it requires
+type Route struct {
+	Pattern    string
+	Permission string
+	// Scope must have been granted to the request's token, if set
+	Scope string
+	// CacheControl is sent with the route's pages when they are cached
+	CacheControl string
+
+	// cache wraps the handler in a page cache, if set
+	cache func(http.Handler) http.Handler
+}
+
+// RouteOption configures a Route at registration time
+type RouteOption func(*Route)
+
+// RequirePermission restricts a route to principals holding permission
+func RequirePermission(permission string) RouteOption {
+	return func(r *Route) {
+		r.Permission = permission
+	}
+}
+
+// RequireScope restricts a route to principals whose token was granted
+// scope. Principals without the scope are refused with an
+// insufficient_scope challenge whatever their roles allow
+func RequireScope(scope string) RouteOption {
+	return func(r *Route) {
+		r.Scope = scope
+	}
+}
+
+// Cached serves the route's pages through c with the cacheControl header.
+// Requests vary decides against are served without the cache
+func Cached(c *pagecache.Cache, cacheControl string, vary pagecache.VaryFunc) RouteOption {
+	return func(r *Route) {
+		r.CacheControl = cacheControl
+		r.cache = func(h http.Handler) http.Handler {
+			return c.Handler(cacheControl, vary, h)
+		}
+	}
+}
+
+// Router is an http.ServeMux that enforces per-route permissions and keeps
+// track of what has been registered
+type Router struct {
+	// Audit records the authorization decisions of permission checks, if set
+	Audit *audit.Logger
+	// LoginURL is where anonymous browsers are sent when a route requires
+	// a permission they lack, with the page to return to in its next
+	// parameter. If empty, or for API clients, they get a 401 challenge
+	LoginURL string
+
+	mux    *http.ServeMux
+	authz  Authorizer
+	routes []Route
+}
+
+// NewRouter returns a Router using authz for routes that require a
+// permission. A nil authz denies all such routes
+func NewRouter(authz Authorizer) *Router {
+	return &Router{mux: http.NewServeMux(), authz: authz}
+}
+
+// Handle registers h for pattern, using the ServeMux pattern syntax
+func (rt *Router) Handle(pattern string, h http.Handler, opts ...RouteOption) {
+	route := Route{Pattern: pattern}
+	for _, opt := range opts {
+		opt(&route)
+	}
+	// The cache sits inside the permission check, which must run on hits
+	if route.cache != nil {
+		h = route.cache(h)
+	}
+	if route.Permission != "" || route.Scope != "" {
+		h = rt.authorize(route, h)
+	}
+	rt.mux.Handle(pattern, h)
+	rt.routes = append(rt.routes, route)
+}
+
+// HandleFunc registers f for pattern
+func (rt *Router) HandleFunc(pattern string, f http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(pattern, f, opts...)
+}
+
+// Routes returns the registered routes in registration order
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+// ServeHTTP dispatches r, answering unknown paths with the 404 error page
+// and known paths with a 405 that lists the methods they accept
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern == "" {
+		if allow := rt.allowed(r); len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			Error(w, r, http.StatusMethodNotAllowed, "")
+			return
+		}
+		Error(w, r, http.StatusNotFound, "")
+		return
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// allowed returns the methods of the routes matching r's path, sorted.
+// Like ServeMux, a GET route also accepts HEAD
+func (rt *Router) allowed(r *http.Request) []string {
+	var allow []string
+	for _, method := range rt.methods() {
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+		if _, pattern := rt.mux.Handler(probe); pattern != "" {
+			allow = append(allow, method)
+		}
+	}
+	return allow
+}
+
+// methods returns the methods named by the registered patterns
+func (rt *Router) methods() []string {
+	seen := make(map[string]bool)
+	for _, route := range rt.routes {
+		method, _, ok := strings.Cut(route.Pattern, " ")
+		if !ok {
+			continue
+		}
+		seen[method] = true
+		if method == http.MethodGet {
+			seen[http.MethodHead] = true
+		}
+	}
+	methods := make([]string, 0, len(seen))
+	for m := range seen {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func (rt *Router) authorize(route Route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := auth.FromContext(r.Context())
+		scoped := route.Scope == "" || p.HasScope(route.Scope)
+		permitted := route.Permission == "" || (rt.authz != nil && rt.authz.Allowed(p, route.Permission))
+		allowed := scoped && permitted
+
+		decision := "deny"
+		if allowed {
+			decision = "allow"
+		}
+		principal := "anonymous"
+		if p != nil {
+			principal = p.Method + ":" + p.ID
+		}
+		log.Printf("authz: request_id=%s principal=%q permission=%q scope=%q route=%q decision=%s",
+			RequestIDFromContext(r.Context()), principal, route.Permission, route.Scope, route.Pattern, decision)
+		details := map[string]string{
+			"permission": route.Permission,
+			"route":      route.Pattern,
+			"decision":   decision,
+		}
+		if route.Scope != "" {
+			details["scope"] = route.Scope
+		}
+		err := rt.Audit.Record(audit.Event{
+			Action:    audit.ActionAuthz,
+			Actor:     principal,
+			RequestID: RequestIDFromContext(r.Context()),
+			Details:   details,
+		})
+		if err != nil {
+			log.Printf("audit: recording %s: %v", audit.ActionAuthz, err)
+		}
+
+		if !allowed && p == nil {
+			rt.unauthenticated(w, r)
+			return
+		}
+		if !scoped {
+			auth.SetChallenge(w.Header(), "insufficient_scope", "missing required scope", route.Scope)
+			Error(w, r, http.StatusForbidden, "The access token lacks the required scope.")
+			return
+		}
+		if !allowed {
+			Error(w, r, http.StatusForbidden, "You do not have permission to access this resource.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unauthenticated asks an anonymous client to sign in: browsers are
+// redirected to the login page, API clients get a 401 challenge
+func (rt *Router) unauthenticated(w http.ResponseWriter, r *http.Request) {
+	if rt.LoginURL != "" && !wantsJSON(r) {
+		// Only pages can be returned to; a form post would be replayed as
+		// a GET after the login
+		next := "/"
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next = r.URL.RequestURI()
+		}
+		http.Redirect(w, r, rt.LoginURL+"?"+url.Values{"next": {next}}.Encode(), http.StatusSeeOther)
+		return
+	}
+	auth.SetChallenge(w.Header(), "", "", "")
+	Error(w, r, http.StatusUnauthorized, "Sign in to access this resource.")
+}
@@ -0,0 +1,353 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/audit"
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/pagecache"
+)
+
+// allowList grants permissions per principal ID
+type allowList map[string][]string
+
+func (a allowList) Allowed(p *auth.Principal, permission string) bool {
+	if p == nil {
+		return false
+	}
+	for _, perm := range a[p.ID] {
+		if perm == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestRouter() *Router {
+	rt := NewRouter(allowList{"admin": {"admin:read"}})
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+	rt.HandleFunc("GET /public", ok)
+	rt.HandleFunc("GET /admin", ok, RequirePermission("admin:read"))
+	rt.HandleFunc("GET /api/v1/admin", ok, RequirePermission("admin:read"))
+	return rt
+}
+
+func serveAs(h http.Handler, p *auth.Principal, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if p != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	}
+	rr := httptest.NewRecorder()
+	RequestID(h).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRouterPermissions(t *testing.T) {
+	rt := newTestRouter()
+	admin := &auth.Principal{ID: "admin"}
+	user := &auth.Principal{ID: "user"}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		target    string
+		status    int
+	}{
+		{"public anonymous", nil, "/public", http.StatusOK},
+		{"protected anonymous", nil, "/admin", http.StatusUnauthorized},
+		{"protected without permission", user, "/admin", http.StatusForbidden},
+		{"protected with permission", admin, "/admin", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serveAs(rt, tc.principal, tc.target)
+			if rr.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestRouterAnonymousSignIn(t *testing.T) {
+	rt := newTestRouter()
+	rt.LoginURL = "/auth/login"
+
+	rr := serveAs(rt, nil, "/admin?tab=keys")
+	if got, want := rr.Code, http.StatusSeeOther; got != want {
+		t.Fatalf("browser status = %d, want %d", got, want)
+	}
+	if got, want := rr.Header().Get("Location"), "/auth/login?next=%2Fadmin%3Ftab%3Dkeys"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	rr = serveAs(rt, nil, "/api/v1/admin")
+	if got, want := rr.Code, http.StatusUnauthorized; got != want {
+		t.Fatalf("API status = %d, want %d", got, want)
+	}
+	if c := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(c, "Bearer ") {
+		t.Errorf("WWW-Authenticate = %q, want a Bearer challenge", c)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want problem JSON", ct)
+	}
+}
+
+func TestRouterDeniedBrowserPage(t *testing.T) {
+	rr := serveAs(newTestRouter(), &auth.Principal{ID: "user"}, "/admin")
+
+	if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("content-type = %q, want text/html", got)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "403 Forbidden") {
+		t.Errorf("body %q does not contain %q", body, "403 Forbidden")
+	}
+	if id := rr.Header().Get(RequestIDHeader); id == "" || !strings.Contains(body, id) {
+		t.Errorf("expected request ID %q in page", id)
+	}
+}
+
+func TestRouterDeniedAPIProblem(t *testing.T) {
+	rr := serveAs(newTestRouter(), &auth.Principal{ID: "user"}, "/api/v1/admin")
+
+	if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Fatalf("content-type = %q, want application/problem+json", got)
+	}
+	var problem Problem
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+		t.Fatalf("invalid problem JSON: %v", err)
+	}
+	if problem.Status != http.StatusForbidden || problem.Instance != "/api/v1/admin" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	if problem.RequestID != rr.Header().Get(RequestIDHeader) {
+		t.Errorf("problem request_id = %q, want %q", problem.RequestID, rr.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestRouterNilAuthorizerDenies(t *testing.T) {
+	rt := NewRouter(nil)
+	rt.HandleFunc("GET /admin", func(w http.ResponseWriter, r *http.Request) {}, RequirePermission("admin:read"))
+
+	if rr := serveAs(rt, &auth.Principal{ID: "admin"}, "/admin"); rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+func TestRouterRoutes(t *testing.T) {
+	routes := newTestRouter().Routes()
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %d", len(routes))
+	}
+	if routes[1].Pattern != "GET /admin" || routes[1].Permission != "admin:read" {
+		t.Errorf("unexpected route %+v", routes[1])
+	}
+}
+
+func TestRouterUnmatched(t *testing.T) {
+	rt := newTestRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	rt.HandleFunc("POST /public", ok)
+	rt.HandleFunc("DELETE /items/{id}", ok)
+	rt.HandleFunc("PUT /items/{id}", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		allow  string
+	}{
+		{"unknown path", http.MethodGet, "/nowhere", http.StatusNotFound, ""},
+		{"wrong method", http.MethodPut, "/public", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{"wildcard path", http.MethodGet, "/items/7", http.StatusMethodNotAllowed, "DELETE, PUT"},
+		{"wildcard too deep", http.MethodGet, "/items/7/parts", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			rr := httptest.NewRecorder()
+			RequestID(rt).ServeHTTP(rr, req)
+
+			if rr.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.status)
+			}
+			if got := rr.Header().Get("Allow"); got != tc.allow {
+				t.Errorf("Allow = %q, want %q", got, tc.allow)
+			}
+			if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+				t.Errorf("content-type = %q, want text/html", got)
+			}
+			if body := rr.Body.String(); !strings.Contains(body, `href="/"`) || !strings.Contains(body, "<footer>") {
+				t.Errorf("themed error page not rendered: %s", body)
+			}
+		})
+	}
+}
+
+func TestRouterUnmatchedAPIProblem(t *testing.T) {
+	rt := newTestRouter()
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/nowhere", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/admin", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Fatalf("%s %s: content-type = %q, want application/problem+json", tc.method, tc.target, got)
+		}
+		var problem Problem
+		if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+			t.Fatalf("invalid problem JSON: %v", err)
+		}
+		if problem.Status != tc.status || problem.Title != http.StatusText(tc.status) {
+			t.Errorf("%s %s: unexpected problem %+v", tc.method, tc.target, problem)
+		}
+	}
+}
+
+func TestRouterUnmatchedLocalized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/nowhere", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rr := httptest.NewRecorder()
+	Locale(newTestRouter()).ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	for _, want := range []string{"404 Page introuvable", "Retour à l&#39;accueil"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+}
+
+func TestRouterAuditsDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(config.AuditConfig{Path: path})
+	if err != nil {
+		t.Fatalf("audit.Open() failed: %v", err)
+	}
+	rt := newTestRouter()
+	rt.Audit = auditLog
+
+	denied := serveAs(rt, &auth.Principal{ID: "user", Method: auth.MethodAPIKey}, "/admin")
+	allowed := serveAs(rt, &auth.Principal{ID: "admin", Method: auth.MethodOIDC}, "/admin")
+	serveAs(rt, nil, "/public")
+	auditLog.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening audit log: %v", err)
+	}
+	defer f.Close()
+	var events []audit.Event
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var e audit.Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("invalid audit record %q: %v", sc.Text(), err)
+		}
+		events = append(events, e)
+	}
+
+	want := []struct {
+		actor, decision string
+		rr              *httptest.ResponseRecorder
+	}{
+		{"api_key:user", "deny", denied},
+		{"oidc:admin", "allow", allowed},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("recorded %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Action != audit.ActionAuthz || e.Actor != w.actor || e.Details["decision"] != w.decision ||
+			e.Details["permission"] != "admin:read" || e.Details["route"] != "GET /admin" {
+			t.Errorf("unexpected audit event %+v", e)
+		}
+		if e.RequestID != w.rr.Header().Get(RequestIDHeader) {
+			t.Errorf("audit request_id = %q, want %q", e.RequestID, w.rr.Header().Get(RequestIDHeader))
+		}
+	}
+}
+
+func TestRouterCached(t *testing.T) {
+	cache := pagecache.New(config.PageCacheConfig{MaxBytes: 1 << 20})
+	renders := 0
+	page := func(w http.ResponseWriter, r *http.Request) {
+		renders++
+		w.Write([]byte("page"))
+	}
+	always := func(*http.Request) (string, bool) { return "", true }
+
+	rt := NewRouter(allowList{"admin": {"admin:read"}})
+	rt.HandleFunc("GET /page", page, Cached(cache, "max-age=60", always))
+	rt.HandleFunc("GET /admin", page, RequirePermission("admin:read"), Cached(cache, "private", always))
+
+	for i := 0; i < 2; i++ {
+		rr := serveAs(rt, nil, "/page")
+		if rr.Code != http.StatusOK || rr.Header().Get("Cache-Control") != "max-age=60" {
+			t.Errorf("status = %d, Cache-Control = %q", rr.Code, rr.Header().Get("Cache-Control"))
+		}
+	}
+	if renders != 1 {
+		t.Errorf("renders = %d, want 1", renders)
+	}
+
+	serveAs(rt, &auth.Principal{ID: "admin"}, "/admin")
+	if rr := serveAs(rt, &auth.Principal{ID: "user"}, "/admin"); rr.Code != http.StatusForbidden {
+		t.Errorf("cached page served without permission: status = %d", rr.Code)
+	}
+	if got := rt.Routes()[0].CacheControl; got != "max-age=60" {
+		t.Errorf("route CacheControl = %q", got)
+	}
+}
+
+func TestRouterRequireScope(t *testing.T) {
+	keys, err := auth.NewAPIKeyStore(config.AuthConfig{APIKeys: []config.APIKeyConfig{
+		{Name: "scoring", Hash: auth.HashAPIKey("scoring-key"), Scopes: []string{ScopePredict}},
+		{Name: "reporting", Hash: auth.HashAPIKey("reporting-key"), Scopes: []string{ScopeModelsRead}},
+	}})
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() failed: %v", err)
+	}
+	rt := NewRouter(allowList{})
+	rt.HandleFunc("POST /api/v1/predict", func(w http.ResponseWriter, r *http.Request) {}, RequireScope(ScopePredict))
+	h := auth.Bearer(keys, Error)(rt)
+
+	for key, want := range map[string]int{"scoring-key": http.StatusOK, "reporting-key": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/predict", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != want {
+			t.Errorf("%s: status = %d, want %d", key, rr.Code, want)
+		}
+		if want == http.StatusForbidden {
+			if c := rr.Header().Get("WWW-Authenticate"); !strings.Contains(c, `error="insufficient_scope"`) || !strings.Contains(c, `scope="predict"`) {
+				t.Errorf("WWW-Authenticate = %q", c)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want problem JSON", ct)
+			}
+		}
+	}
+}
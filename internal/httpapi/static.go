@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/Elenetta17/iris-web-service/internal/assets"
+)
+
+//go:generate go run ../../cmd/precompress static
+
+// StaticPrefix is the URL path the static files are served under
+const StaticPrefix = "/static/"
+
+//go:embed static
+var staticFS embed.FS
+
+var staticAssets = func() *assets.Store {
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		panic(err)
+	}
+	s, err := assets.New(sub, StaticPrefix)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}()
+
+// Static serves the embedded static files. Mount it at StaticPrefix
+func Static() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !staticAssets.Has(r.URL.Path) {
+			Error(w, r, http.StatusNotFound, "")
+			return
+		}
+		staticAssets.ServeHTTP(w, r)
+	})
+}
+
+// assetURL is the asset template function: the fingerprinted URL of a
+// static file, e.g. {{asset "app.css"}}
+func assetURL(name string) (string, error) {
+	return staticAssets.URL(name)
+}
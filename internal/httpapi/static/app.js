@@ -0,0 +1,7 @@
+// Ask before submitting forms marked with data-confirm, such as deletes
+document.addEventListener("submit", function (event) {
+    var message = event.target.getAttribute("data-confirm");
+    if (message && !window.confirm(message)) {
+        event.preventDefault();
+    }
+});
@@ -0,0 +1,62 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/assets"
+)
+
+func TestPagesLinkFingerprintedAssets(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Render(rr, httptest.NewRequest(http.MethodGet, "/", nil), "form.html", FormData{})
+
+	links := regexp.MustCompile(`(?:href|src)="(/static/[^"]+)"`).FindAllStringSubmatch(rr.Body.String(), -1)
+	if len(links) != 3 {
+		t.Fatalf("found %d asset links, want 3", len(links))
+	}
+	for _, m := range links {
+		req := httptest.NewRequest(http.MethodGet, m[1], nil)
+		req.Header.Set("Accept-Encoding", "br, gzip")
+		res := httptest.NewRecorder()
+		Static().ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", m[1], res.Code)
+		}
+		if got := res.Header().Get("Cache-Control"); got != assets.CacheImmutable {
+			t.Errorf("%s: Cache-Control = %q", m[1], got)
+		}
+	}
+}
+
+func TestStaticNotFound(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Static().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, StaticPrefix+"missing.css", nil))
+
+	if got, want := rr.Code, http.StatusNotFound; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want the error page", ct)
+	}
+}
+
+// The committed variants must be regenerated with go generate whenever
+// the static files change; stale ones are ignored at startup
+func TestStaticVariantsUpToDate(t *testing.T) {
+	for _, name := range []string{"app.css", "app.js", "favicon.svg"} {
+		u, err := assetURL(name)
+		if err != nil {
+			t.Fatalf("assetURL(%q) failed: %v", name, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		req.Header.Set("Accept-Encoding", "br")
+		rr := httptest.NewRecorder()
+		Static().ServeHTTP(rr, req)
+		if got := rr.Header().Get("Content-Encoding"); got != "br" {
+			t.Errorf("%s: Content-Encoding = %q, want br; run go generate ./internal/httpapi", name, got)
+		}
+	}
+}
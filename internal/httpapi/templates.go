@@ -0,0 +1,205 @@
+package httpapi
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+// Page templates live in templates/pages and define the blocks of the base
+// layout in templates/layouts; templates/partials are shared by all pages
+//
+//go:embed templates
+var templateFS embed.FS
+
+// EmbeddedTemplates returns the templates compiled into the binary, rooted
+// like the templates directory
+func EmbeddedTemplates() fs.FS {
+	sub, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// TemplateLoader parses the page templates from a file system with
+// layouts/, partials/ and pages/ directories, once per supported locale. A
+// reloading loader re-parses them whenever a file changes, for development
+type TemplateLoader struct {
+	fsys   fs.FS
+	reload bool
+
+	mu sync.Mutex
+	// pages maps a locale to its template sets
+	pages map[string]map[string]*template.Template
+	err   error
+	// stamp fingerprints the files the pages were parsed from
+	stamp string
+}
+
+// NewTemplateLoader parses the templates in fsys. A reloading loader
+// starts even if they do not parse, so the error can be shown in the
+// browser and fixed without a restart
+func NewTemplateLoader(fsys fs.FS, reload bool) (*TemplateLoader, error) {
+	l := &TemplateLoader{fsys: fsys, reload: reload}
+	if _, err := l.Pages(); err != nil && !reload {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Pages returns the template set of every page in the default locale,
+// keyed by file name such as "form.html"
+func (l *TemplateLoader) Pages() (map[string]*template.Template, error) {
+	return l.PagesIn(i18n.DefaultLocale)
+}
+
+// PagesIn is like Pages for the catalog of locale, which must be one of
+// i18n.Locales
+func (l *TemplateLoader) PagesIn(locale string) (map[string]*template.Template, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pages != nil && !l.reload {
+		return l.pages[locale], nil
+	}
+	stamp, err := fingerprint(l.fsys)
+	if err != nil {
+		return nil, err
+	}
+	if l.pages == nil || stamp != l.stamp {
+		l.pages, l.err = parseLocalizedPages(l.fsys)
+		l.stamp = stamp
+	}
+	return l.pages[locale], l.err
+}
+
+// fingerprint summarizes the names, sizes and modification times of the
+// files in fsys
+func fingerprint(fsys fs.FS) (string, error) {
+	var b strings.Builder
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// templateFuncs are available to every template. T, locale and greetee
+// are placeholders until the pages are bound to a catalog
+var templateFuncs = template.FuncMap{
+	"T":       i18n.Default().T,
+	"locale":  func() string { return i18n.DefaultLocale },
+	"greetee": greetee(i18n.Default()),
+	"locales": i18n.Locales,
+	"asset":   assetURL,
+	"flashes": func(data any) []Flash {
+		if fc, ok := data.(flashCarrier); ok {
+			return fc.FlashMessages()
+		}
+		return nil
+	},
+}
+
+// parseLocalizedPages parses the pages for every supported locale, binding
+// T and locale to its catalog
+func parseLocalizedPages(fsys fs.FS) (map[string]map[string]*template.Template, error) {
+	sets := make(map[string]map[string]*template.Template)
+	for _, locale := range i18n.Locales() {
+		c := i18n.Lookup(locale)
+		set, err := parsePages(fsys, template.FuncMap{
+			"T":       c.T,
+			"locale":  func() string { return c.Locale },
+			"greetee": greetee(c),
+		})
+		if err != nil {
+			return nil, err
+		}
+		sets[locale] = set
+	}
+	return sets, nil
+}
+
+// greetee returns the name a greeting is addressed to in c's language.
+// Greetings without a name are stored as such and greet the default name
+func greetee(c *i18n.Catalog) func(name string) string {
+	return func(name string) string {
+		if name == "" {
+			return c.T("hello.default_name")
+		}
+		return name
+	}
+}
+
+// parsePages parses the layouts and partials once, then clones them for
+// every page so that the pages' block definitions do not collide
+func parsePages(fsys fs.FS, funcs template.FuncMap) (map[string]*template.Template, error) {
+	base, err := template.New("").Funcs(templateFuncs).Funcs(funcs).ParseFS(fsys, "layouts/*.html", "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	paths, err := fs.Glob(fsys, "pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]*template.Template, len(paths))
+	for _, p := range paths {
+		t, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.ParseFS(fsys, p); err != nil {
+			return nil, err
+		}
+		set[path.Base(p)] = t
+	}
+	return set, nil
+}
+
+var templates atomic.Pointer[TemplateLoader]
+
+func init() {
+	l, err := NewTemplateLoader(EmbeddedTemplates(), false)
+	if err != nil {
+		panic(err)
+	}
+	templates.Store(l)
+}
+
+// UseTemplates makes Render use l instead of the embedded templates
+func UseTemplates(l *TemplateLoader) {
+	templates.Store(l)
+}
+
+// templateErrorPage is independent of the template files, which are the
+// thing that is broken when it is shown
+var templateErrorPage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Template error</title></head>
+<body>
+    <h1>Template error</h1>
+    <pre>{{.}}</pre>
+    <p>Fix the template and reload the page.</p>
+</body>
+</html>
+`))
+
+func writeTemplateError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	templateErrorPage.Execute(w, err.Error())
+}
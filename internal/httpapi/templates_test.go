@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func writeTemplates(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func renderPage(t *testing.T, l *TemplateLoader, page string) string {
+	t.Helper()
+	pages, err := l.Pages()
+	if err != nil {
+		t.Fatalf("Pages() failed: %v", err)
+	}
+	var b strings.Builder
+	if err := pages[page].ExecuteTemplate(&b, "base", nil); err != nil {
+		t.Fatalf("executing %s: %v", page, err)
+	}
+	return b.String()
+}
+
+func TestTemplateLoaderReloads(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, map[string]string{
+		"layouts/base.html": `{{define "base"}}[{{block "content" .}}{{end}}]{{end}}`,
+		"partials/nav.html": `{{define "nav"}}{{end}}`,
+		"pages/home.html":   `{{define "content"}}v1{{end}}`,
+	})
+	l, err := NewTemplateLoader(os.DirFS(dir), true)
+	if err != nil {
+		t.Fatalf("NewTemplateLoader() failed: %v", err)
+	}
+	if got := renderPage(t, l, "home.html"); got != "[v1]" {
+		t.Fatalf("page = %q, want [v1]", got)
+	}
+
+	writeTemplates(t, dir, map[string]string{"pages/home.html": `{{define "content"}}v2, edited{{end}}`})
+	if got := renderPage(t, l, "home.html"); got != "[v2, edited]" {
+		t.Errorf("page after edit = %q, want [v2, edited]", got)
+	}
+
+	writeTemplates(t, dir, map[string]string{"pages/home.html": `{{define "content"}}{{.Broken{{end}}`})
+	if _, err := l.Pages(); err == nil {
+		t.Error("expected parse error after breaking the template")
+	}
+
+	writeTemplates(t, dir, map[string]string{"pages/home.html": `{{define "content"}}fixed!{{end}}`})
+	if got := renderPage(t, l, "home.html"); got != "[fixed!]" {
+		t.Errorf("page after fix = %q, want [fixed!]", got)
+	}
+}
+
+func TestTemplateLoaderWithoutReload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`{{define "base"}}{{block "content" .}}{{end}}{{end}}`)},
+		"partials/nav.html": {Data: []byte(`{{define "nav"}}{{end}}`)},
+		"pages/home.html":   {Data: []byte(`{{define "content"}}v1{{end}}`)},
+	}
+	l, err := NewTemplateLoader(fsys, false)
+	if err != nil {
+		t.Fatalf("NewTemplateLoader() failed: %v", err)
+	}
+	fsys["pages/home.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}changed{{end}}`)}
+	if got := renderPage(t, l, "home.html"); got != "v1" {
+		t.Errorf("page = %q, want the templates parsed at startup", got)
+	}
+
+	fsys["pages/broken.html"] = &fstest.MapFile{Data: []byte(`{{if}}`)}
+	if _, err := NewTemplateLoader(fsys, false); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestRenderShowsTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, map[string]string{
+		"layouts/base.html": `{{define "base"}}{{block "content" .}}{{end}}{{end}}`,
+		"partials/nav.html": `{{define "nav"}}{{end}}`,
+		"pages/home.html":   `{{define "content"}}{{if}}{{end}}`,
+	})
+	l, err := NewTemplateLoader(os.DirFS(dir), true)
+	if err != nil {
+		t.Fatalf("NewTemplateLoader() failed: %v", err)
+	}
+	defer UseTemplates(templates.Load())
+	UseTemplates(l)
+
+	rr := httptest.NewRecorder()
+	Render(rr, httptest.NewRequest(http.MethodGet, "/", nil), "home.html", nil)
+	if got, want := rr.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Template error") || !strings.Contains(body, "home.html") {
+		t.Errorf("parse error not shown: %s", body)
+	}
+}
+
+func TestEmbeddedTemplates(t *testing.T) {
+	l, err := NewTemplateLoader(EmbeddedTemplates(), false)
+	if err != nil {
+		t.Fatalf("NewTemplateLoader() failed: %v", err)
+	}
+	pages, _ := l.Pages()
+	for _, page := range []string{"error.html", "form.html", "predict.html"} {
+		if pages[page] == nil {
+			t.Errorf("embedded templates lack %s", page)
+		}
+	}
+}
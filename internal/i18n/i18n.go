@@ -0,0 +1,209 @@
+// Package i18n translates the messages of the browser UI. Catalogs are
+// embedded JSON files in locales/, one per language
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when no supported language is requested and for
+// messages missing from other catalogs
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// message holds the plural forms of a translation, keyed by CLDR category
+// ("one", "other"). Messages without plural forms only have "other"
+type message map[string]string
+
+func (m *message) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = message{"other": s}
+		return nil
+	}
+	var forms map[string]string
+	if err := json.Unmarshal(b, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms["other"]; !ok {
+		return fmt.Errorf("plural message lacks the %q form", "other")
+	}
+	*m = forms
+	return nil
+}
+
+// pluralRules picks the CLDR plural category of n for each language.
+// Languages not listed use the English rule
+var pluralRules = map[string]func(n int) string{
+	"en": oneIfExactlyOne,
+	"de": oneIfExactlyOne,
+	"fr": func(n int) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+func oneIfExactlyOne(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// Catalog holds the translations of one language. It is immutable and
+// safe for concurrent use
+type Catalog struct {
+	Locale   string
+	messages map[string]message
+	fallback *Catalog
+}
+
+var catalogs = mustLoad()
+
+func mustLoad() map[string]*Catalog {
+	paths, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	set := make(map[string]*Catalog, len(paths))
+	for _, p := range paths {
+		data, err := localeFS.ReadFile("locales/" + p.Name())
+		if err != nil {
+			panic(err)
+		}
+		locale := strings.TrimSuffix(p.Name(), path.Ext(p.Name()))
+		c := &Catalog{Locale: locale}
+		if err := json.Unmarshal(data, &c.messages); err != nil {
+			panic(fmt.Sprintf("i18n: parsing %s: %v", p.Name(), err))
+		}
+		set[locale] = c
+	}
+	def, ok := set[DefaultLocale]
+	if !ok {
+		panic("i18n: no catalog for " + DefaultLocale)
+	}
+	for _, c := range set {
+		if c != def {
+			c.fallback = def
+		}
+	}
+	return set
+}
+
+// Locales returns the supported languages, sorted
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the catalog of locale, or nil if it is not supported.
+// Region subtags are ignored, so "fr-CA" finds the French catalog
+func Lookup(locale string) *Catalog {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(locale)), "-")
+	base, _, _ = strings.Cut(base, "_")
+	return catalogs[base]
+}
+
+// Default returns the catalog of DefaultLocale
+func Default() *Catalog {
+	return catalogs[DefaultLocale]
+}
+
+// T translates key, formatting args into it with fmt verbs. When the
+// message has plural forms the first argument must be an int and selects
+// the form. Keys missing from every catalog are returned as is so that
+// they stand out on the page
+func (c *Catalog) T(key string, args ...any) string {
+	m, cat := c.find(key)
+	if m == nil {
+		return key
+	}
+	format := m["other"]
+	if len(m) > 1 && len(args) > 0 {
+		if n, ok := args[0].(int); ok {
+			if f, ok := m[cat.plural(n)]; ok {
+				format = f
+			}
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Has reports whether key is translated in c or its fallback
+func (c *Catalog) Has(key string) bool {
+	m, _ := c.find(key)
+	return m != nil
+}
+
+// find returns the message for key and the catalog it came from
+func (c *Catalog) find(key string) (message, *Catalog) {
+	for cat := c; cat != nil; cat = cat.fallback {
+		if m, ok := cat.messages[key]; ok {
+			return m, cat
+		}
+	}
+	return nil, nil
+}
+
+func (c *Catalog) plural(n int) string {
+	rule, ok := pluralRules[c.Locale]
+	if !ok {
+		rule = oneIfExactlyOne
+	}
+	return rule(n)
+}
+
+// Negotiate picks the supported catalog the client prefers according to
+// an Accept-Language header, falling back to DefaultLocale
+func Negotiate(acceptLanguage string) *Catalog {
+	best, bestQ := Default(), 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if c := Lookup(tag); c != nil && q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+type catalogKey struct{}
+
+// NewContext returns a copy of ctx carrying c
+func NewContext(ctx context.Context, c *Catalog) context.Context {
+	return context.WithValue(ctx, catalogKey{}, c)
+}
+
+// FromContext returns the catalog stored by NewContext, or the default
+// catalog
+func FromContext(ctx context.Context) *Catalog {
+	if c, ok := ctx.Value(catalogKey{}).(*Catalog); ok {
+		return c
+	}
+	return Default()
+}
@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCatalogsHaveEveryKey(t *testing.T) {
+	def := Default()
+	for _, locale := range Locales() {
+		c := Lookup(locale)
+		for key, m := range def.messages {
+			got, ok := c.messages[key]
+			if !ok {
+				t.Errorf("%s lacks %q", locale, key)
+				continue
+			}
+			if len(got) != len(m) {
+				t.Errorf("%s: %q has %d forms, want %d", locale, key, len(got), len(m))
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{"en", "hello.greeting", []any{"Ada"}, "Hello Ada!"},
+		{"fr", "hello.greeting", []any{"Ada"}, "Bonjour Ada !"},
+		{"de", "hello.greeting", []any{"Ada"}, "Hallo Ada!"},
+		{"fr", "form.submit", nil, "Dire bonjour"},
+		{"en", "greetings.count", []any{0}, "0 greetings so far"},
+		{"en", "greetings.count", []any{1}, "1 greeting so far"},
+		{"en", "greetings.count", []any{2}, "2 greetings so far"},
+		{"fr", "greetings.count", []any{0}, "0 salutation jusqu'ici"},
+		{"fr", "greetings.count", []any{2}, "2 salutations jusqu'ici"},
+		{"de", "greetings.count", []any{1}, "Bisher 1 Gruß"},
+		{"de", "no.such.key", nil, "no.such.key"},
+	}
+	for _, tc := range tests {
+		if got := Lookup(tc.locale).T(tc.key, tc.args...); got != tc.want {
+			t.Errorf("%s: T(%q, %v) = %q, want %q", tc.locale, tc.key, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestTFallsBackToDefault(t *testing.T) {
+	c := &Catalog{Locale: "xx", messages: map[string]message{}, fallback: Default()}
+	if got, want := c.T("hello.greeting", "Ada"), "Hello Ada!"; got != want {
+		t.Errorf("T() = %q, want %q", got, want)
+	}
+}
+
+func TestHas(t *testing.T) {
+	fr := Lookup("fr")
+	if !fr.Has("hello.greeting") {
+		t.Error(`Has("hello.greeting") = false, want true`)
+	}
+	if fr.Has("no.such.key") {
+		t.Error(`Has("no.such.key") = true, want false`)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"fr", "fr"},
+		{"fr-CA", "fr"},
+		{"DE_at", "de"},
+		{"es", ""},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		got := ""
+		if c := Lookup(tc.locale); c != nil {
+			got = c.Locale
+		}
+		if got != tc.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tc.locale, got, tc.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr-FR,fr;q=0.9,en;q=0.8", "fr"},
+		{"es, de;q=0.5", "de"},
+		{"en;q=0.2, de;q=0.7", "de"},
+		{"de;q=0, es", "en"},
+		{"ja", "en"},
+	}
+	for _, tc := range tests {
+		if got := Negotiate(tc.header).Locale; got != tc.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != Default() {
+		t.Errorf("FromContext() = %q, want the default catalog", got.Locale)
+	}
+	fr := Lookup("fr")
+	if got := FromContext(NewContext(context.Background(), fr)); got != fr {
+		t.Errorf("FromContext() = %q, want fr", got.Locale)
+	}
+}
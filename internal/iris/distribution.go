@@ -0,0 +1,93 @@
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultBins is the number of quantile bins recorded per feature
+const DefaultBins = 10
+
+// FeatureDistribution describes how one measurement was distributed in the
+// training data. Edges are the cut points between bins: value x falls in
+// bin i when Edges[i-1] <= x < Edges[i]
+type FeatureDistribution struct {
+	Name        string    `json:"name"`
+	Edges       []float64 `json:"edges"`
+	Proportions []float64 `json:"proportions"`
+	Mean        float64   `json:"mean"`
+	Std         float64   `json:"std"`
+}
+
+// Bin returns the index of the bin containing x
+func (d FeatureDistribution) Bin(x float64) int {
+	return sort.Search(len(d.Edges), func(i int) bool { return x < d.Edges[i] })
+}
+
+// Distribution is the training distribution stored in a model artifact so
+// that live inputs can be compared against it
+type Distribution struct {
+	Features []FeatureDistribution `json:"features"`
+}
+
+// NewDistribution summarizes samples with up to bins quantile bins per
+// feature. Repeated values can merge bins, so some features get fewer
+func NewDistribution(samples []Sample, bins int) *Distribution {
+	mean, std := meanStd(samples)
+	d := &Distribution{}
+	for i, name := range FeatureNames {
+		values := make([]float64, len(samples))
+		for j, s := range samples {
+			values[j] = s.Vector()[i]
+		}
+		sort.Float64s(values)
+
+		fd := FeatureDistribution{Name: name, Mean: mean[i], Std: std[i]}
+		for b := 1; b < bins; b++ {
+			edge := values[b*len(values)/bins]
+			if edge > values[0] && (len(fd.Edges) == 0 || edge > fd.Edges[len(fd.Edges)-1]) {
+				fd.Edges = append(fd.Edges, edge)
+			}
+		}
+		fd.Proportions = make([]float64, len(fd.Edges)+1)
+		for _, v := range values {
+			fd.Proportions[fd.Bin(v)]++
+		}
+		for b := range fd.Proportions {
+			fd.Proportions[b] /= float64(len(values))
+		}
+		d.Features = append(d.Features, fd)
+	}
+	return d
+}
+
+func (d *Distribution) check() error {
+	if len(d.Features) != len(FeatureNames) {
+		return fmt.Errorf("training distribution needs %d features", len(FeatureNames))
+	}
+	for i, fd := range d.Features {
+		if fd.Name != FeatureNames[i] {
+			return fmt.Errorf("training distribution feature %d is %q, want %q", i, fd.Name, FeatureNames[i])
+		}
+		if len(fd.Proportions) != len(fd.Edges)+1 {
+			return errors.New("training distribution needs one more proportion than edges")
+		}
+	}
+	return nil
+}
+
+var (
+	datasetDistOnce sync.Once
+	datasetDist     *Distribution
+)
+
+// DatasetDistribution is the distribution of the bundled dataset, which
+// the bundled model was built from
+func DatasetDistribution() *Distribution {
+	datasetDistOnce.Do(func() {
+		datasetDist = NewDistribution(Dataset(), DefaultBins)
+	})
+	return datasetDist
+}
@@ -0,0 +1,50 @@
+package iris
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewDistribution(t *testing.T) {
+	d := NewDistribution(Dataset(), DefaultBins)
+	if len(d.Features) != len(FeatureNames) {
+		t.Fatalf("got %d features, want %d", len(d.Features), len(FeatureNames))
+	}
+	for _, fd := range d.Features {
+		if len(fd.Edges) == 0 || len(fd.Edges) >= DefaultBins {
+			t.Errorf("%s: got %d edges, want 1 to %d", fd.Name, len(fd.Edges), DefaultBins-1)
+		}
+		sum := 0.0
+		for i, p := range fd.Proportions {
+			if p <= 0 {
+				t.Errorf("%s: bin %d is empty", fd.Name, i)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%s: proportions sum to %v, want 1", fd.Name, sum)
+		}
+	}
+	if err := d.check(); err != nil {
+		t.Errorf("check() = %v", err)
+	}
+}
+
+func TestFeatureDistributionBin(t *testing.T) {
+	fd := FeatureDistribution{Edges: []float64{1, 2, 3}}
+	tests := []struct {
+		x    float64
+		want int
+	}{
+		{0.5, 0},
+		{1, 1},
+		{2.5, 2},
+		{3, 3},
+		{10, 3},
+	}
+	for _, tt := range tests {
+		if got := fd.Bin(tt.x); got != tt.want {
+			t.Errorf("Bin(%v) = %d, want %d", tt.x, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// Evaluation summarizes how well a classifier predicts held-out samples
+type Evaluation struct {
+	Accuracy float64
+	Classes  []string
+	// Confusion[i][j] counts samples of Classes[i] predicted as Classes[j]
+	Confusion [][]int
+}
+
+// Trainer fits a classifier to samples
+type Trainer func(samples []Sample) (Classifier, error)
+
+// CrossValidate estimates the accuracy of models built by train with
+// k-fold cross-validation. Samples are shuffled with a fixed seed first,
+// so results are reproducible
+func CrossValidate(samples []Sample, folds int, train Trainer) (Evaluation, error) {
+	if folds < 2 {
+		return Evaluation{}, errors.New("need at least 2 folds")
+	}
+	if len(samples) < folds {
+		return Evaluation{}, fmt.Errorf("%d samples are too few for %d folds", len(samples), folds)
+	}
+
+	shuffled := append([]Sample(nil), samples...)
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	ev := Evaluation{Classes: classesOf(samples)}
+	index := make(map[string]int, len(ev.Classes))
+	ev.Confusion = make([][]int, len(ev.Classes))
+	for i, c := range ev.Classes {
+		index[c] = i
+		ev.Confusion[i] = make([]int, len(ev.Classes))
+	}
+
+	correct := 0
+	for fold := 0; fold < folds; fold++ {
+		lo, hi := fold*len(shuffled)/folds, (fold+1)*len(shuffled)/folds
+		training := append(append([]Sample(nil), shuffled[:lo]...), shuffled[hi:]...)
+		model, err := train(training)
+		if err != nil {
+			return Evaluation{}, fmt.Errorf("fold %d: %w", fold+1, err)
+		}
+		for _, s := range shuffled[lo:hi] {
+			got := model.Predict(s.Features).Species
+			if got == s.Species {
+				correct++
+			}
+			// A class missing from a fold's training data cannot be
+			// predicted, so got is always one of ev.Classes
+			ev.Confusion[index[s.Species]][index[got]]++
+		}
+	}
+	ev.Accuracy = float64(correct) / float64(len(shuffled))
+	return ev, nil
+}
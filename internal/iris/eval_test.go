@@ -0,0 +1,42 @@
+package iris
+
+import "testing"
+
+func TestCrossValidate(t *testing.T) {
+	train := func(s []Sample) (Classifier, error) { return NewKNN(s, DefaultK), nil }
+	ev, err := CrossValidate(Dataset(), 5, train)
+	if err != nil {
+		t.Fatalf("CrossValidate() failed: %v", err)
+	}
+	if ev.Accuracy < 0.9 {
+		t.Errorf("accuracy = %.3f, want at least 0.9", ev.Accuracy)
+	}
+
+	total, diagonal := 0, 0
+	for i, row := range ev.Confusion {
+		for j, n := range row {
+			total += n
+			if i == j {
+				diagonal += n
+			}
+		}
+	}
+	if total != 150 {
+		t.Errorf("confusion matrix counts %d samples, want 150", total)
+	}
+	if got := float64(diagonal) / float64(total); got != ev.Accuracy {
+		t.Errorf("confusion matrix accuracy %.3f disagrees with Accuracy %.3f", got, ev.Accuracy)
+	}
+
+	again, _ := CrossValidate(Dataset(), 5, train)
+	if again.Accuracy != ev.Accuracy {
+		t.Error("cross-validation is not reproducible")
+	}
+
+	if _, err := CrossValidate(Dataset(), 1, train); err == nil {
+		t.Error("expected error for a single fold")
+	}
+	if _, err := CrossValidate(Dataset()[:3], 5, train); err == nil {
+		t.Error("expected error for fewer samples than folds")
+	}
+}
@@ -0,0 +1,72 @@
+package iris
+
+// Explanation methods
+const (
+	ExplainCoefficients = "coefficients"
+	ExplainNeighbours   = "neighbours"
+)
+
+// Explanation tells why a classifier predicted a species
+type Explanation struct {
+	Species string `json:"species"`
+	Method  string `json:"method"`
+	// Contributions split the predicted species' score between the
+	// features; the Intercept is the part not owed to any of them
+	Contributions []Contribution `json:"contributions,omitempty"`
+	Intercept     float64        `json:"intercept,omitempty"`
+	// Neighbours are the training samples that voted, nearest first
+	Neighbours []Neighbour `json:"neighbours,omitempty"`
+}
+
+// Contribution is one feature's part in a linear score
+type Contribution struct {
+	Feature string  `json:"feature"`
+	Value   float64 `json:"value"`
+	// Coefficient weighs the standardized value
+	Coefficient  float64 `json:"coefficient"`
+	Contribution float64 `json:"contribution"`
+}
+
+// Explainer is a Classifier that can justify its predictions
+type Explainer interface {
+	Classifier
+	// Explain accounts for the score of species when classifying f
+	Explain(f Features, species string) Explanation
+}
+
+var (
+	_ Explainer = (*Model)(nil)
+	_ Explainer = (*KNN)(nil)
+)
+
+// Explain attributes the score of species to the features as coefficient
+// times standardized input. Positive contributions favour species
+func (m *Model) Explain(f Features, species string) Explanation {
+	e := Explanation{Species: species, Method: ExplainCoefficients}
+	k := -1
+	for i, c := range m.Classes {
+		if c == species {
+			k = i
+		}
+	}
+	if k < 0 {
+		return e
+	}
+	w := m.Weights[k]
+	v := f.Vector()
+	for j, x := range m.scale(v) {
+		e.Contributions = append(e.Contributions, Contribution{
+			Feature:      FeatureNames[j],
+			Value:        v[j],
+			Coefficient:  w[j],
+			Contribution: w[j] * x,
+		})
+	}
+	e.Intercept = w[len(v)]
+	return e
+}
+
+// Explain lists the neighbours that voted on f
+func (m *KNN) Explain(f Features, species string) Explanation {
+	return Explanation{Species: species, Method: ExplainNeighbours, Neighbours: m.Neighbours(f)}
+}
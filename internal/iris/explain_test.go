@@ -0,0 +1,54 @@
+package iris
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModelExplain(t *testing.T) {
+	m := trainDefault(t)
+	f := Features{6.0, 2.9, 4.5, 1.5}
+	p := m.Predict(f)
+	e := m.Explain(f, p.Species)
+
+	if e.Method != ExplainCoefficients || e.Species != p.Species || len(e.Contributions) != len(FeatureNames) {
+		t.Fatalf("Explain() = %+v", e)
+	}
+	// The contributions and intercept add up to the score of the class
+	x := m.scale(f.Vector())
+	var k int
+	for i, c := range m.Classes {
+		if c == p.Species {
+			k = i
+		}
+	}
+	want := m.Weights[k][len(x)]
+	for j, v := range x {
+		want += m.Weights[k][j] * v
+	}
+	got := e.Intercept
+	for i, c := range e.Contributions {
+		if c.Feature != FeatureNames[i] || c.Value != f.Vector()[i] {
+			t.Errorf("contribution %d = %+v", i, c)
+		}
+		got += c.Contribution
+	}
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("contributions sum to %v, want score %v", got, want)
+	}
+
+	if e := m.Explain(f, "rose"); len(e.Contributions) != 0 {
+		t.Errorf("Explain() of unknown species = %+v", e)
+	}
+}
+
+func TestKNNExplain(t *testing.T) {
+	f := Features{5.1, 3.5, 1.4, 0.2}
+	e := Default().Explain(f, Setosa)
+	if e.Method != ExplainNeighbours || len(e.Neighbours) != DefaultK {
+		t.Fatalf("Explain() = %+v", e)
+	}
+	if e.Neighbours[0].Distance != 0 || e.Neighbours[0].Species != Setosa {
+		t.Errorf("nearest neighbour = %+v, want the identical setosa sample", e.Neighbours[0])
+	}
+}
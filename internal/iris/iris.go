@@ -0,0 +1,211 @@
+// Package iris classifies iris flowers by their sepal and petal
+// measurements and bundles Fisher's Iris dataset
+package iris
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Species names, in the order used for class indices
+const (
+	Setosa     = "setosa"
+	Versicolor = "versicolor"
+	Virginica  = "virginica"
+)
+
+// Species lists every class a model can predict
+var Species = []string{Setosa, Versicolor, Virginica}
+
+// FeatureNames are the measurement names used in CSV headers, JSON bodies
+// and form fields, in vector order
+var FeatureNames = []string{"sepal_length", "sepal_width", "petal_length", "petal_width"}
+
+// Range is the accepted interval (Min, Max] for a measurement in cm
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// Ranges bounds each measurement, indexed like FeatureNames. They are
+// deliberately wider than the dataset so that unusual flowers can still be
+// scored, but reject typos such as a length in millimetres
+var Ranges = []Range{
+	{Min: 0, Max: 15},
+	{Min: 0, Max: 10},
+	{Min: 0, Max: 15},
+	{Min: 0, Max: 5},
+}
+
+// Features are the four measurements of a flower, in cm
+type Features struct {
+	SepalLength float64 `json:"sepal_length"`
+	SepalWidth  float64 `json:"sepal_width"`
+	PetalLength float64 `json:"petal_length"`
+	PetalWidth  float64 `json:"petal_width"`
+}
+
+// Vector returns the measurements in FeatureNames order
+func (f Features) Vector() []float64 {
+	return []float64{f.SepalLength, f.SepalWidth, f.PetalLength, f.PetalWidth}
+}
+
+// FieldError describes an invalid measurement
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid measurement of a Features value
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate returns a ValidationError if any measurement is outside Ranges
+func (f Features) Validate() error {
+	var errs ValidationError
+	for i, v := range f.Vector() {
+		r := Ranges[i]
+		if !(v > r.Min && v <= r.Max) {
+			errs = append(errs, FieldError{
+				Field:   FeatureNames[i],
+				Message: fmt.Sprintf("must be greater than %g and at most %g cm", r.Min, r.Max),
+			})
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Sample is a labelled flower
+type Sample struct {
+	Features
+	Species string `json:"species"`
+}
+
+// Prediction is a classifier's answer for one flower
+type Prediction struct {
+	Species string `json:"species"`
+	// Probabilities has an entry for every class and sums to 1
+	Probabilities map[string]float64 `json:"probabilities"`
+}
+
+// Classifier predicts the species of a flower
+type Classifier interface {
+	Predict(f Features) Prediction
+}
+
+//go:embed data/iris.csv
+var dataFS embed.FS
+
+var (
+	datasetOnce sync.Once
+	dataset     []Sample
+)
+
+// Dataset returns the 150 samples of Fisher's Iris dataset. Callers must
+// not modify the returned slice
+func Dataset() []Sample {
+	datasetOnce.Do(func() {
+		f, err := dataFS.Open("data/iris.csv")
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		dataset, err = ReadCSV(f)
+		if err != nil {
+			panic(fmt.Sprintf("embedded iris dataset: %v", err))
+		}
+	})
+	return dataset
+}
+
+// ReadCSV parses labelled samples from CSV with a header naming the four
+// measurements and a species column, in any order. Species must be one of
+// Species, optionally prefixed with "Iris-"
+func ReadCSV(r io.Reader) ([]Sample, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range append(FeatureNames, "species") {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var samples []Sample
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var v [4]float64
+		for i, name := range FeatureNames {
+			v[i], err = strconv.ParseFloat(strings.TrimSpace(rec[cols[name]]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, rec[cols[name]])
+			}
+		}
+		species := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rec[cols["species"]])), "iris-")
+		if species == "" {
+			return nil, fmt.Errorf("line %d: empty species", line)
+		}
+		if !slices.Contains(Species, species) {
+			return nil, fmt.Errorf("line %d: unknown species %q", line, rec[cols["species"]])
+		}
+		samples = append(samples, Sample{
+			Features: Features{SepalLength: v[0], SepalWidth: v[1], PetalLength: v[2], PetalWidth: v[3]},
+			Species:  species,
+		})
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("no samples")
+	}
+	return samples, nil
+}
+
+// WriteCSV writes samples in the format read by ReadCSV
+func WriteCSV(w io.Writer, samples []Sample) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string(nil), FeatureNames...), "species")); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		rec := make([]string, 0, len(FeatureNames)+1)
+		for _, v := range s.Vector() {
+			rec = append(rec, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := cw.Write(append(rec, s.Species)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
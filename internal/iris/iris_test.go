@@ -0,0 +1,87 @@
+package iris
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDataset(t *testing.T) {
+	samples := Dataset()
+	if len(samples) != 150 {
+		t.Fatalf("len(Dataset()) = %d, want 150", len(samples))
+	}
+	counts := make(map[string]int)
+	for _, s := range samples {
+		counts[s.Species]++
+		if err := s.Validate(); err != nil {
+			t.Errorf("dataset sample %+v fails validation: %v", s, err)
+		}
+	}
+	for _, sp := range Species {
+		if counts[sp] != 50 {
+			t.Errorf("%s has %d samples, want 50", sp, counts[sp])
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	in := "species,petal_width,petal_length,sepal_width,sepal_length\nIris-setosa,0.2,1.4,3.5,5.1\n"
+	samples, err := ReadCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadCSV() failed: %v", err)
+	}
+	want := Sample{Features{5.1, 3.5, 1.4, 0.2}, Setosa}
+	if len(samples) != 1 || samples[0] != want {
+		t.Errorf("ReadCSV() = %+v, want [%+v]", samples, want)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"missing column", "sepal_length,sepal_width,petal_length,species\n5.1,3.5,1.4,setosa\n"},
+		{"bad number", "sepal_length,sepal_width,petal_length,petal_width,species\n5.1,x,1.4,0.2,setosa\n"},
+		{"empty species", "sepal_length,sepal_width,petal_length,petal_width,species\n5.1,3.5,1.4,0.2,\n"},
+		{"unknown species", "sepal_length,sepal_width,petal_length,petal_width,species\n5.1,3.5,1.4,0.2,iris-sibirica\n"},
+		{"no rows", "sepal_length,sepal_width,petal_length,petal_width,species\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCSV(strings.NewReader(tt.in)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := WriteCSV(&b, Dataset()[:2]); err != nil {
+		t.Fatalf("WriteCSV() failed: %v", err)
+	}
+	want := "sepal_length,sepal_width,petal_length,petal_width,species\n5.1,3.5,1.4,0.2,setosa\n4.9,3,1.4,0.2,setosa\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+	back, err := ReadCSV(strings.NewReader(b.String()))
+	if err != nil || len(back) != 2 || back[1] != Dataset()[1] {
+		t.Errorf("ReadCSV(WriteCSV()) = %+v, %v", back, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Features{5.1, 3.5, 1.4, 0.2}).Validate(); err != nil {
+		t.Errorf("Validate() on a real flower = %v", err)
+	}
+
+	err := (Features{0, 3.5, 51, 0.2}).Validate()
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v, want ValidationError", err)
+	}
+	if len(verr) != 2 || verr[0].Field != "sepal_length" || verr[1].Field != "petal_length" {
+		t.Errorf("unexpected field errors %+v", verr)
+	}
+}
@@ -0,0 +1,132 @@
+package iris
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefaultK is the number of neighbours consulted by the bundled model
+const DefaultK = 5
+
+// KNN is a k-nearest-neighbours classifier. Measurements are standardized
+// before computing Euclidean distances so that the wide petal length range
+// does not dominate the narrow petal width one
+type KNN struct {
+	k       int
+	samples []Sample
+	points  [][]float64
+	mean    []float64
+	std     []float64
+}
+
+// NewKNN returns a classifier that votes among the k training samples
+// closest to the flower being classified
+func NewKNN(samples []Sample, k int) *KNN {
+	if k < 1 {
+		k = 1
+	}
+	if k > len(samples) {
+		k = len(samples)
+	}
+	m := &KNN{k: k, samples: samples}
+	m.mean, m.std = meanStd(samples)
+	m.points = make([][]float64, len(samples))
+	for i, s := range samples {
+		m.points[i] = m.scale(s.Vector())
+	}
+	return m
+}
+
+func meanStd(samples []Sample) (mean, std []float64) {
+	n := len(FeatureNames)
+	mean = make([]float64, n)
+	std = make([]float64, n)
+	for _, s := range samples {
+		for i, v := range s.Vector() {
+			mean[i] += v
+		}
+	}
+	for i := range mean {
+		mean[i] /= float64(len(samples))
+	}
+	for _, s := range samples {
+		for i, v := range s.Vector() {
+			std[i] += (v - mean[i]) * (v - mean[i])
+		}
+	}
+	for i := range std {
+		std[i] = math.Sqrt(std[i] / float64(len(samples)))
+		if std[i] == 0 {
+			std[i] = 1
+		}
+	}
+	return mean, std
+}
+
+func (m *KNN) scale(v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i := range v {
+		out[i] = (v[i] - m.mean[i]) / m.std[i]
+	}
+	return out
+}
+
+// Neighbour is a training sample near the flower being classified
+type Neighbour struct {
+	Sample
+	Distance float64 `json:"distance"`
+}
+
+// Neighbours returns the k training samples closest to f, nearest first
+func (m *KNN) Neighbours(f Features) []Neighbour {
+	x := m.scale(f.Vector())
+	all := make([]Neighbour, len(m.samples))
+	for i, p := range m.points {
+		var d float64
+		for j := range p {
+			d += (p[j] - x[j]) * (p[j] - x[j])
+		}
+		all[i] = Neighbour{Sample: m.samples[i], Distance: math.Sqrt(d)}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Distance < all[j].Distance })
+	return all[:m.k]
+}
+
+// Predict implements Classifier. Probabilities are the share of the k
+// neighbours voting for each species; ties go to the species of the
+// nearest neighbour among the tied ones
+func (m *KNN) Predict(f Features) Prediction {
+	neighbours := m.Neighbours(f)
+	votes := make(map[string]int)
+	for _, n := range neighbours {
+		votes[n.Species]++
+	}
+
+	p := Prediction{Probabilities: make(map[string]float64, len(Species))}
+	for _, s := range Species {
+		p.Probabilities[s] = 0
+	}
+	best := 0
+	for _, n := range neighbours {
+		p.Probabilities[n.Species] = float64(votes[n.Species]) / float64(len(neighbours))
+		if votes[n.Species] > best {
+			best = votes[n.Species]
+			p.Species = n.Species
+		}
+	}
+	return p
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultModel *KNN
+)
+
+// Default returns the bundled model: k-NN over the embedded dataset
+func Default() *KNN {
+	defaultOnce.Do(func() {
+		defaultModel = NewKNN(Dataset(), DefaultK)
+	})
+	return defaultModel
+}
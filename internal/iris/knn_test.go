@@ -0,0 +1,64 @@
+package iris
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKNNPredict(t *testing.T) {
+	m := Default()
+	tests := []struct {
+		features Features
+		want     string
+	}{
+		{Features{5.1, 3.5, 1.4, 0.2}, Setosa},
+		{Features{6.0, 2.9, 4.5, 1.5}, Versicolor},
+		{Features{7.7, 3.0, 6.1, 2.3}, Virginica},
+	}
+	for _, tt := range tests {
+		p := m.Predict(tt.features)
+		if p.Species != tt.want {
+			t.Errorf("Predict(%+v) = %s, want %s", tt.features, p.Species, tt.want)
+		}
+		var sum float64
+		for _, sp := range Species {
+			prob, ok := p.Probabilities[sp]
+			if !ok {
+				t.Errorf("no probability for %s", sp)
+			}
+			sum += prob
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("probabilities sum to %v, want 1", sum)
+		}
+	}
+}
+
+// TestKNNLeaveOneOut guards the quality of the bundled model
+func TestKNNLeaveOneOut(t *testing.T) {
+	data := Dataset()
+	correct := 0
+	for i, s := range data {
+		rest := append(append([]Sample(nil), data[:i]...), data[i+1:]...)
+		if NewKNN(rest, DefaultK).Predict(s.Features).Species == s.Species {
+			correct++
+		}
+	}
+	if acc := float64(correct) / float64(len(data)); acc < 0.93 {
+		t.Errorf("leave-one-out accuracy = %.3f, want at least 0.93", acc)
+	}
+}
+
+func TestKNNNeighbours(t *testing.T) {
+	m := NewKNN(Dataset(), 3)
+	n := m.Neighbours(Features{5.1, 3.5, 1.4, 0.2})
+	if len(n) != 3 {
+		t.Fatalf("len(Neighbours()) = %d, want 3", len(n))
+	}
+	if n[0].Distance != 0 || n[0].Species != Setosa {
+		t.Errorf("nearest neighbour = %+v, want the identical setosa sample", n[0])
+	}
+	if n[1].Distance < n[0].Distance || n[2].Distance < n[1].Distance {
+		t.Error("neighbours not sorted by distance")
+	}
+}
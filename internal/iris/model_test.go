@@ -0,0 +1,99 @@
+package iris
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func trainDefault(t *testing.T) *Model {
+	t.Helper()
+	m, err := TrainLogistic(Dataset(), DefaultTrainOptions)
+	if err != nil {
+		t.Fatalf("TrainLogistic() failed: %v", err)
+	}
+	return m
+}
+
+func TestTrainLogistic(t *testing.T) {
+	m := trainDefault(t)
+	correct := 0
+	for _, s := range Dataset() {
+		p := m.Predict(s.Features)
+		if p.Species == s.Species {
+			correct++
+		}
+		var sum float64
+		for _, prob := range p.Probabilities {
+			sum += prob
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("probabilities sum to %v, want 1", sum)
+		}
+	}
+	if acc := float64(correct) / 150; acc < 0.95 {
+		t.Errorf("training accuracy = %.3f, want at least 0.95", acc)
+	}
+
+	if _, err := TrainLogistic(nil, DefaultTrainOptions); err == nil {
+		t.Error("expected error without samples")
+	}
+	if _, err := TrainLogistic(Dataset()[:50], DefaultTrainOptions); err == nil {
+		t.Error("expected error with a single species")
+	}
+}
+
+func TestModelSaveLoad(t *testing.T) {
+	m := trainDefault(t)
+	m.Version = "v1"
+	m.CreatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.Accuracy = 0.97
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	loaded, err := LoadModel(path)
+	if err != nil {
+		t.Fatalf("LoadModel() failed: %v", err)
+	}
+	if loaded.Version != "v1" || !loaded.CreatedAt.Equal(m.CreatedAt) || loaded.Accuracy != 0.97 {
+		t.Errorf("metadata not preserved: %+v", loaded)
+	}
+	if loaded.Training == nil || len(loaded.Training.Features) != len(FeatureNames) {
+		t.Errorf("training distribution not preserved: %+v", loaded.Training)
+	}
+	f := Features{6.0, 2.9, 4.5, 1.5}
+	if got, want := loaded.Predict(f), m.Predict(f); got.Species != want.Species || got.Probabilities[Versicolor] != want.Probabilities[Versicolor] {
+		t.Errorf("loaded model predicts %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadModelRejectsInvalidArtifacts(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(m *Model)
+	}{
+		{"format version", func(m *Model) { m.FormatVersion = 99 }},
+		{"algorithm", func(m *Model) { m.Algorithm = "svm" }},
+		{"features", func(m *Model) { m.Features = []string{"a", "b", "c", "d"} }},
+		{"weights", func(m *Model) { m.Weights = m.Weights[:1] }},
+		{"std", func(m *Model) { m.Std = []float64{1, 0, 1, 1} }},
+		{"training", func(m *Model) { m.Training.Features[0].Proportions = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := trainDefault(t)
+			tt.mutate(m)
+			data, _ := json.Marshal(m)
+			path := filepath.Join(t.TempDir(), "model.json")
+			os.WriteFile(path, data, 0o644)
+			if _, err := LoadModel(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BundledVersion is the version reported for the k-NN model built into
+// the binary
+const BundledVersion = "bundled-knn"
+
+// ErrUnknownVersion is returned when a model version is not in the registry
+var ErrUnknownVersion = errors.New("unknown model version")
+
+// ModelInfo is the metadata listed for a registered model
+type ModelInfo struct {
+	Version   string    `json:"version"`
+	Algorithm string    `json:"algorithm"`
+	Accuracy  float64   `json:"accuracy,omitempty"`
+	Samples   int       `json:"samples,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Entry is a registered model
+type Entry struct {
+	ModelInfo
+	Classifier Classifier `json:"-"`
+	// Training is the distribution the model was trained on, or nil if the
+	// artifact does not record it
+	Training *Distribution `json:"-"`
+}
+
+// EntryFor wraps a trained model artifact
+func EntryFor(m *Model) Entry {
+	return Entry{
+		ModelInfo: ModelInfo{
+			Version:   m.Version,
+			Algorithm: m.Algorithm,
+			Accuracy:  m.Accuracy,
+			Samples:   m.Samples,
+			CreatedAt: m.CreatedAt,
+		},
+		Classifier: m,
+		Training:   m.Training,
+	}
+}
+
+// BundledEntry wraps the model returned by Default
+func BundledEntry() Entry {
+	return Entry{
+		ModelInfo:  ModelInfo{Version: BundledVersion, Algorithm: "knn", Samples: len(Dataset())},
+		Classifier: Default(),
+		Training:   DatasetDistribution(),
+	}
+}
+
+// Routing decides which model serves a prediction. CandidateWeight of the
+// traffic goes to Candidate and the rest to Primary
+type Routing struct {
+	Primary         string  `json:"primary"`
+	Candidate       string  `json:"candidate,omitempty"`
+	CandidateWeight float64 `json:"candidate_weight,omitempty"`
+}
+
+// registryState is replaced as a whole so readers never see a half-applied
+// promotion
+type registryState struct {
+	entries map[string]*Entry
+	routing Routing
+}
+
+// Registry holds the models that can serve predictions and routes traffic
+// between them. It is safe for concurrent use; changes take effect
+// atomically for subsequent predictions
+type Registry struct {
+	dir   string
+	state atomic.Pointer[registryState]
+	// mu serializes writers so that concurrent updates are not lost
+	mu   sync.Mutex
+	rand func() float64
+}
+
+// NewRegistry returns a registry of entries. An empty routing.Primary
+// selects the most recently created model
+func NewRegistry(entries []Entry, routing Routing) (*Registry, error) {
+	r := &Registry{rand: rand.Float64}
+	if err := r.replace(entries, routing); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadRegistry registers every model artifact (*.json) in dir
+func LoadRegistry(dir string, routing Routing) (*Registry, error) {
+	entries, err := loadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRegistry(entries, routing)
+	if err != nil {
+		return nil, err
+	}
+	r.dir = dir
+	return r, nil
+}
+
+func loadDir(dir string) ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("model directory: %w", err)
+		}
+		return nil, fmt.Errorf("no model artifacts in %s", dir)
+	}
+	entries := make([]Entry, 0, len(paths))
+	for _, path := range paths {
+		m, err := LoadModel(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, EntryFor(m))
+	}
+	return entries, nil
+}
+
+func (r *Registry) replace(entries []Entry, routing Routing) error {
+	if len(entries) == 0 {
+		return errors.New("registry needs at least one model")
+	}
+	entries = append([]Entry(nil), entries...)
+	st := &registryState{entries: make(map[string]*Entry, len(entries))}
+	for i := range entries {
+		e := &entries[i]
+		if e.Version == "" {
+			return errors.New("model without a version")
+		}
+		if _, dup := st.entries[e.Version]; dup {
+			return fmt.Errorf("duplicate model version %q", e.Version)
+		}
+		st.entries[e.Version] = e
+	}
+	if routing.Primary == "" {
+		routing.Primary = newest(entries).Version
+	}
+	if err := st.validate(routing); err != nil {
+		return err
+	}
+	st.routing = routing
+	r.state.Store(st)
+	return nil
+}
+
+func newest(entries []Entry) Entry {
+	best := entries[0]
+	for _, e := range entries[1:] {
+		if e.CreatedAt.After(best.CreatedAt) {
+			best = e
+		}
+	}
+	return best
+}
+
+func (st *registryState) validate(routing Routing) error {
+	if _, ok := st.entries[routing.Primary]; !ok {
+		return fmt.Errorf("primary: %w %q", ErrUnknownVersion, routing.Primary)
+	}
+	if routing.CandidateWeight < 0 || routing.CandidateWeight > 1 {
+		return errors.New("candidate_weight must be between 0 and 1")
+	}
+	if routing.Candidate == "" {
+		if routing.CandidateWeight != 0 {
+			return errors.New("candidate_weight requires a candidate")
+		}
+		return nil
+	}
+	if routing.Candidate == routing.Primary {
+		return errors.New("candidate must differ from primary")
+	}
+	if _, ok := st.entries[routing.Candidate]; !ok {
+		return fmt.Errorf("candidate: %w %q", ErrUnknownVersion, routing.Candidate)
+	}
+	return nil
+}
+
+// Reload re-reads the model directory so that newly trained models can be
+// promoted without a restart. The routing is kept; Reload fails and leaves
+// the registry unchanged if a routed version has disappeared. Registries
+// not loaded from a directory are left unchanged
+func (r *Registry) Reload() error {
+	if r.dir == "" {
+		return nil
+	}
+	entries, err := loadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.replace(entries, r.state.Load().routing)
+}
+
+// List returns the registered models, newest first
+func (r *Registry) List() []ModelInfo {
+	st := r.state.Load()
+	out := make([]ModelInfo, 0, len(st.entries))
+	for _, e := range st.entries {
+		out = append(out, e.ModelInfo)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// Routing returns the current traffic split
+func (r *Registry) Routing() Routing {
+	return r.state.Load().routing
+}
+
+// Promote makes version the primary model. A candidate equal to version
+// is cleared, since it now receives all traffic
+func (r *Registry) Promote(version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routing := r.state.Load().routing
+	routing.Primary = version
+	if routing.Candidate == version {
+		routing.Candidate, routing.CandidateWeight = "", 0
+	}
+	return r.setRouting(routing)
+}
+
+// SetRouting replaces the traffic split
+func (r *Registry) SetRouting(routing Routing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.setRouting(routing)
+}
+
+// setRouting must be called with r.mu held
+func (r *Registry) setRouting(routing Routing) error {
+	old := r.state.Load()
+	if err := old.validate(routing); err != nil {
+		return err
+	}
+	r.state.Store(&registryState{entries: old.entries, routing: routing})
+	return nil
+}
+
+// Select picks the model to serve one request according to the routing
+func (r *Registry) Select() *Entry {
+	st := r.state.Load()
+	if st.routing.Candidate != "" && r.rand() < st.routing.CandidateWeight {
+		return st.entries[st.routing.Candidate]
+	}
+	return st.entries[st.routing.Primary]
+}
+
+var (
+	bundledOnce     sync.Once
+	bundledRegistry *Registry
+)
+
+// BundledRegistry returns a registry serving only the bundled model
+func BundledRegistry() *Registry {
+	bundledOnce.Do(func() {
+		r, err := NewRegistry([]Entry{BundledEntry()}, Routing{})
+		if err != nil {
+			panic(err)
+		}
+		bundledRegistry = r
+	})
+	return bundledRegistry
+}
@@ -0,0 +1,149 @@
+package iris
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeModel saves a quickly trained artifact to dir
+func writeModel(t *testing.T, dir, version string, created time.Time) {
+	t.Helper()
+	m, err := TrainLogistic(Dataset(), TrainOptions{Epochs: 10, LearningRate: 0.5})
+	if err != nil {
+		t.Fatalf("TrainLogistic() failed: %v", err)
+	}
+	m.Version, m.CreatedAt, m.Accuracy = version, created, 0.9
+	if err := m.Save(filepath.Join(dir, version+".json")); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeModel(t, dir, "v1", t0)
+	writeModel(t, dir, "v2", t0.Add(time.Hour))
+
+	reg, err := LoadRegistry(dir, Routing{})
+	if err != nil {
+		t.Fatalf("LoadRegistry() failed: %v", err)
+	}
+	list := reg.List()
+	if len(list) != 2 || list[0].Version != "v2" || list[1].Accuracy != 0.9 {
+		t.Errorf("List() = %+v, want v2 then v1", list)
+	}
+	if got := reg.Routing().Primary; got != "v2" {
+		t.Errorf("default primary = %q, want the newest model v2", got)
+	}
+	if got := reg.Select().Version; got != "v2" {
+		t.Errorf("Select() = %q, want v2", got)
+	}
+
+	if _, err := LoadRegistry(dir, Routing{Primary: "v9"}); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("LoadRegistry() with unknown primary = %v, want ErrUnknownVersion", err)
+	}
+	if _, err := LoadRegistry(t.TempDir(), Routing{}); err == nil {
+		t.Error("expected error for an empty directory")
+	}
+}
+
+func TestRegistryRouting(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeModel(t, dir, "v1", t0)
+	writeModel(t, dir, "v2", t0.Add(time.Hour))
+	reg, err := LoadRegistry(dir, Routing{Primary: "v1", Candidate: "v2", CandidateWeight: 0.25})
+	if err != nil {
+		t.Fatalf("LoadRegistry() failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		roll float64
+		want string
+	}{{0.1, "v2"}, {0.24, "v2"}, {0.25, "v1"}, {0.9, "v1"}} {
+		reg.rand = func() float64 { return tt.roll }
+		if got := reg.Select().Version; got != tt.want {
+			t.Errorf("Select() with roll %v = %q, want %q", tt.roll, got, tt.want)
+		}
+	}
+
+	invalid := []Routing{
+		{Primary: "v3"},
+		{Primary: "v1", Candidate: "v1", CandidateWeight: 0.5},
+		{Primary: "v1", Candidate: "v2", CandidateWeight: 1.5},
+		{Primary: "v1", CandidateWeight: 0.5},
+	}
+	for _, r := range invalid {
+		if err := reg.SetRouting(r); err == nil {
+			t.Errorf("SetRouting(%+v) succeeded", r)
+		}
+	}
+	if got := reg.Routing(); got.Primary != "v1" || got.Candidate != "v2" {
+		t.Errorf("routing changed by invalid updates: %+v", got)
+	}
+
+	if err := reg.Promote("v2"); err != nil {
+		t.Fatalf("Promote() failed: %v", err)
+	}
+	if got := reg.Routing(); got != (Routing{Primary: "v2"}) {
+		t.Errorf("routing after promoting the candidate = %+v, want v2 only", got)
+	}
+	if err := reg.Promote("v9"); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Promote(unknown) = %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeModel(t, dir, "v1", t0)
+	reg, err := LoadRegistry(dir, Routing{})
+	if err != nil {
+		t.Fatalf("LoadRegistry() failed: %v", err)
+	}
+
+	writeModel(t, dir, "v2", t0.Add(time.Hour))
+	if err := reg.Promote("v2"); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("Promote() before Reload = %v, want ErrUnknownVersion", err)
+	}
+	if err := reg.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if got := reg.Routing().Primary; got != "v1" {
+		t.Errorf("Reload() changed the primary to %q", got)
+	}
+	if err := reg.Promote("v2"); err != nil {
+		t.Errorf("Promote() after Reload failed: %v", err)
+	}
+}
+
+func TestRegistryConcurrentPromote(t *testing.T) {
+	a := Entry{ModelInfo: ModelInfo{Version: "a"}, Classifier: Default()}
+	b := Entry{ModelInfo: ModelInfo{Version: "b"}, Classifier: Default()}
+	reg, err := NewRegistry([]Entry{a, b}, Routing{Primary: "a"})
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			reg.Promote([]string{"a", "b"}[i%2])
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if e := reg.Select(); e == nil || e.Classifier == nil {
+					t.Error("Select() returned an incomplete entry")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
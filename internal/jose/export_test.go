@@ -0,0 +1,8 @@
+package jose
+
+import "time"
+
+// SetClock replaces the clock c uses to age its key set
+func SetClock(c *JWKSCache, now func() time.Time) {
+	c.now = now
+}
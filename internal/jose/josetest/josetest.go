@@ -0,0 +1,111 @@
+// Package josetest signs tokens and describes keys as JWKs for tests of
+// code that verifies them
+package josetest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+)
+
+// Sign serializes claims as a compact JWT signed with key. The algorithm
+// is taken from header.Alg; key is a []byte secret for HS256 and the
+// matching private key otherwise
+func Sign(header jose.Header, claims any, key crypto.PrivateKey) (string, error) {
+	if header.Typ == "" {
+		header.Typ = "JWT"
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch header.Alg {
+	case jose.HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("HS256 requires a []byte secret")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case jose.RS256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("RS256 requires an RSA key")
+		}
+		sig, err = rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+	case jose.ES256:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("ES256 requires an EC key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return "", err
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	default:
+		return "", fmt.Errorf("%w: %q", jose.ErrUnsupportedAlg, header.Alg)
+	}
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// NewJWK describes a public key as a JWK with the given kid
+func NewJWK(kid string, key crypto.PublicKey) (jose.JWK, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return jose.JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: jose.RS256,
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(bigEndian(k.E)),
+		}, nil
+	case *ecdsa.PublicKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		return jose.JWK{
+			Kty: "EC",
+			Kid: kid,
+			Use: "sig",
+			Alg: jose.ES256,
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(x),
+			Y:   base64.RawURLEncoding.EncodeToString(y),
+		}, nil
+	}
+	return jose.JWK{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+func bigEndian(n int) []byte {
+	var out []byte
+	for n > 0 {
+		out = append([]byte{byte(n)}, out...)
+		n >>= 8
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package jose
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when no key matches the token's kid
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// minRefreshInterval limits how often an unknown kid or a failing
+// endpoint can trigger a refetch
+const minRefreshInterval = 10 * time.Second
+
+// fetchTimeout bounds a key set fetch, which is shared by the requests
+// waiting on it and so does not use their contexts
+const fetchTimeout = 10 * time.Second
+
+// JWK is a single JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set document
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey converts the JWK to an *rsa.PublicKey or *ecdsa.PublicKey
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: exponent: %w", k.Kid, err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: x: %w", k.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: y: %w", k.Kid, err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+	return nil, fmt.Errorf("jwk %q: unsupported key type %q", k.Kid, k.Kty)
+}
+
+// JWKSCache fetches a remote key set and keeps it for a configurable TTL.
+// A token signed with an unknown kid triggers an early refresh so that
+// key rotation at the provider is picked up without waiting for expiry.
+// Lookups are not blocked by a fetch in progress, and concurrent refreshes
+// share a single request. While the endpoint fails, known keys keep being
+// served and fetches are retried at most every minRefreshInterval
+type JWKSCache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	failedAt  time.Time
+	lastErr   error
+	inflight  *jwksFetch
+	now       func() time.Time
+}
+
+// jwksFetch is a key set request shared by the callers waiting on it
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSCache returns a cache for the key set published at url
+func NewJWKSCache(url string, ttl time.Duration, client *http.Client) *JWKSCache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &JWKSCache{url: url, ttl: ttl, client: client, now: time.Now}
+}
+
+// Key returns the public key identified by kid
+func (c *JWKSCache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	now := c.now()
+	age := now.Sub(c.fetchedAt)
+	stale := c.keys == nil || age >= c.ttl || (!ok && age >= minRefreshInterval)
+	err := c.lastErr
+	backoff := err != nil && now.Sub(c.failedAt) < minRefreshInterval
+	c.mu.Unlock()
+
+	if stale {
+		if !backoff {
+			err = c.refresh(ctx)
+		}
+		if err == nil {
+			c.mu.Lock()
+			key, ok = c.keys[kid]
+			c.mu.Unlock()
+		} else if !ok {
+			return nil, err
+		}
+		// Otherwise keep serving the stale key while the endpoint fails
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the key set without holding c.mu, joining the fetch
+// already in progress if there is one
+func (c *JWKSCache) refresh(ctx context.Context) error {
+	c.mu.Lock()
+	f := c.inflight
+	if f == nil {
+		f = &jwksFetch{done: make(chan struct{})}
+		c.inflight = f
+		go c.run(context.WithoutCancel(ctx), f)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run performs the fetch f and stores its result
+func (c *JWKSCache) run(ctx context.Context, f *jwksFetch) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	keys, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.keys = keys
+		c.fetchedAt = c.now()
+		c.lastErr = nil
+	} else {
+		c.failedAt = c.now()
+		c.lastErr = err
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+}
+
+func (c *JWKSCache) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys we cannot use instead of rejecting the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,244 @@
+package jose_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+	"github.com/Elenetta17/iris-web-service/internal/jose/josetest"
+)
+
+func TestJWKSCache(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	ecKey := mustEC(t)
+
+	rsaJWK, _ := josetest.NewJWK("rsa-1", &rsaKey.PublicKey)
+	ecJWK, _ := josetest.NewJWK("ec-1", &ecKey.PublicKey)
+
+	var fetches atomic.Int32
+	set := jose.JWKSet{Keys: []jose.JWK{rsaJWK}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	cache := jose.NewJWKSCache(srv.URL, time.Hour, srv.Client())
+	jose.SetClock(cache, func() time.Time { return now })
+
+	key, err := cache.Key(context.Background(), "rsa-1")
+	if err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+	if pub, ok := key.(*rsa.PublicKey); !ok || !pub.Equal(&rsaKey.PublicKey) {
+		t.Errorf("unexpected key %v", key)
+	}
+
+	// Cached: no second fetch
+	if _, err := cache.Key(context.Background(), "rsa-1"); err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected 1 fetch, got %d", got)
+	}
+
+	// Unknown kid right after a fetch does not hammer the provider
+	if _, err := cache.Key(context.Background(), "ec-1"); !errors.Is(err, jose.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected 1 fetch, got %d", got)
+	}
+
+	// After the minimum interval a rotated key is picked up
+	set.Keys = append(set.Keys, ecJWK)
+	now = now.Add(time.Minute)
+	key, err = cache.Key(context.Background(), "ec-1")
+	if err != nil {
+		t.Fatalf("Key() after rotation failed: %v", err)
+	}
+	if pub, ok := key.(*ecdsa.PublicKey); !ok || !pub.Equal(&ecKey.PublicKey) {
+		t.Errorf("unexpected key %v", key)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWKSCacheFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cache := jose.NewJWKSCache(srv.URL, time.Hour, srv.Client())
+	if _, err := cache.Key(context.Background(), "any"); err == nil {
+		t.Error("expected error from failing JWKS endpoint")
+	}
+}
+
+func TestJWKSCacheSlowRefresh(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	rsaJWK, _ := josetest.NewJWK("rsa-1", &rsaKey.PublicKey)
+
+	var fetches atomic.Int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			started <- struct{}{}
+			<-release
+		}
+		json.NewEncoder(w).Encode(jose.JWKSet{Keys: []jose.JWK{rsaJWK}})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	now := time.Unix(1_700_000_000, 0)
+	var clock sync.Mutex
+	cache := jose.NewJWKSCache(srv.URL, time.Hour, srv.Client())
+	jose.SetClock(cache, func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	})
+	if _, err := cache.Key(context.Background(), "rsa-1"); err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+	clock.Lock()
+	now = now.Add(time.Minute)
+	clock.Unlock()
+
+	// Two lookups of an unknown kid share one stalled refresh
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cache.Key(context.Background(), "rotated")
+			errs <- err
+		}()
+	}
+	<-started
+
+	// Known keys are still served while the refresh is stalled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cache.Key(ctx, "rsa-1"); err != nil {
+		t.Errorf("Key() during refresh failed: %v", err)
+	}
+
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, jose.ErrUnknownKey) {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWKSCacheEndpointDown(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	rsaJWK, _ := josetest.NewJWK("rsa-1", &rsaKey.PublicKey)
+
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(jose.JWKSet{Keys: []jose.JWK{rsaJWK}})
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	cache := jose.NewJWKSCache(srv.URL, time.Minute, srv.Client())
+	jose.SetClock(cache, func() time.Time { return now })
+	if _, err := cache.Key(context.Background(), "rsa-1"); err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+
+	// Once the keys expire, a failing endpoint leaves the stale keys in use
+	// and is retried at most every minRefreshInterval
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 5; i++ {
+		if _, err := cache.Key(context.Background(), "rsa-1"); err != nil {
+			t.Errorf("Key() with the endpoint down failed: %v", err)
+		}
+		if _, err := cache.Key(context.Background(), "unknown"); err == nil {
+			t.Error("expected an error for an unknown kid")
+		}
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("expected 2 fetches, got %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	cache.Key(context.Background(), "rsa-1")
+	if got := fetches.Load(); got != 3 {
+		t.Errorf("expected a retry after the interval, got %d fetches", got)
+	}
+}
+
+func TestJWKSCacheSharedFetchOutlivesCaller(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	rsaJWK, _ := josetest.NewJWK("rsa-1", &rsaKey.PublicKey)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		json.NewEncoder(w).Encode(jose.JWKSet{Keys: []jose.JWK{rsaJWK}})
+	}))
+	defer srv.Close()
+
+	cache := jose.NewJWKSCache(srv.URL, time.Hour, srv.Client())
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.Key(ctx, "rsa-1")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := cache.Key(context.Background(), "rsa-1")
+		second <- err
+	}()
+
+	// The caller that started the fetch gives up; the one that joined it
+	// still gets the keys
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("first Key() = %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("joined Key() failed: %v", err)
+	}
+}
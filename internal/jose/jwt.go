@@ -0,0 +1,214 @@
+// Package jose implements the subset of JWS/JWT/JWK needed to verify
+// tokens issued by identity providers and gateways
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("token expired")
+	ErrNotYetValid      = errors.New("token not yet valid")
+	ErrInvalidIssuer    = errors.New("invalid issuer")
+	ErrInvalidAudience  = errors.New("invalid audience")
+)
+
+// Header is the JOSE header of a token
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Claims holds the decoded JWT claims set
+type Claims map[string]any
+
+// Token is a parsed but not yet verified JWT
+type Token struct {
+	Header Header
+	Claims Claims
+
+	signingInput string
+	signature    []byte
+}
+
+// Parse decodes a compact serialized JWT without verifying it
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	tok := &Token{signingInput: parts[0] + "." + parts[1]}
+	if err := decodeSegment(parts[0], &tok.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	if err := decodeSegment(parts[1], &tok.Claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+	tok.signature = sig
+	return tok, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Verify checks the token signature with key, which must match the
+// algorithm declared in the header: a []byte secret for HS256, an
+// *rsa.PublicKey for RS256 or an *ecdsa.PublicKey for ES256
+func (t *Token) Verify(key crypto.PublicKey) error {
+	digest := sha256.Sum256([]byte(t.signingInput))
+
+	switch t.Header.Alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return fmt.Errorf("%w: HS256 requires a shared secret", ErrInvalidSignature)
+		}
+		if !hmac.Equal(t.signature, hmacSHA256(secret, t.signingInput)) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 requires an RSA key", ErrInvalidSignature)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], t.signature); err != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: ES256 requires an EC key", ErrInvalidSignature)
+		}
+		// JWS uses the fixed-size r||s encoding rather than ASN.1
+		if len(t.signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(t.signature[:32])
+		s := new(big.Int).SetBytes(t.signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlg, t.Header.Alg)
+	}
+	return nil
+}
+
+// Expectations are the registered claim checks applied by Validate
+type Expectations struct {
+	Issuer   string
+	Audience string
+	Now      time.Time
+	Skew     time.Duration
+}
+
+// Validate checks iss, aud, exp and nbf against e. exp is required, and an
+// empty issuer or audience expectation matches no token
+func (c Claims) Validate(e Expectations) error {
+	if e.Issuer == "" || c.String("iss") != e.Issuer {
+		return ErrInvalidIssuer
+	}
+	if e.Audience == "" || !c.HasAudience(e.Audience) {
+		return ErrInvalidAudience
+	}
+
+	exp, ok := c.Time("exp")
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrMalformed)
+	}
+	if !e.Now.Before(exp.Add(e.Skew)) {
+		return ErrExpired
+	}
+	if nbf, ok := c.Time("nbf"); ok && e.Now.Add(e.Skew).Before(nbf) {
+		return ErrNotYetValid
+	}
+	return nil
+}
+
+// String returns the string claim name, or "" if absent
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim that is either a string or an array of strings.
+// Space-separated strings (as used by the OAuth "scope" claim) are split
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// HasAudience reports whether aud is listed in the aud claim
+func (c Claims) HasAudience(aud string) bool {
+	for _, a := range c.Strings("aud") {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Time returns a NumericDate claim
+func (c Claims) Time(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+func hmacSHA256(secret []byte, input string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,167 @@
+package jose_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/jose"
+	"github.com/Elenetta17/iris-web-service/internal/jose/josetest"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+
+	secret := []byte("shared-secret")
+
+	tests := []struct {
+		alg  string
+		priv crypto.PrivateKey
+		pub  crypto.PublicKey
+	}{
+		{jose.HS256, secret, secret},
+		{jose.RS256, rsaKey, &rsaKey.PublicKey},
+		{jose.ES256, ecKey, &ecKey.PublicKey},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.alg, func(t *testing.T) {
+			raw, err := josetest.Sign(jose.Header{Alg: tc.alg, Kid: "k1"}, map[string]any{"sub": "alice"}, tc.priv)
+			if err != nil {
+				t.Fatalf("Sign() failed: %v", err)
+			}
+
+			tok, err := jose.Parse(raw)
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if tok.Header.Kid != "k1" || tok.Claims.String("sub") != "alice" {
+				t.Errorf("unexpected token %+v", tok)
+			}
+			if err := tok.Verify(tc.pub); err != nil {
+				t.Errorf("Verify() failed: %v", err)
+			}
+
+			// Tamper with the claims segment
+			parts := strings.Split(raw, ".")
+			forged, _ := josetest.Sign(jose.Header{Alg: tc.alg, Kid: "k1"}, map[string]any{"sub": "mallory"}, tc.priv)
+			parts[1] = strings.Split(forged, ".")[1]
+			tok, err = jose.Parse(strings.Join(parts, "."))
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if err := tok.Verify(tc.pub); !errors.Is(err, jose.ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyWrongKeyType(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	raw, err := josetest.Sign(jose.Header{Alg: jose.RS256}, map[string]any{}, rsaKey)
+	if err != nil {
+		t.Fatalf("Sign() failed: %v", err)
+	}
+	tok, _ := jose.Parse(raw)
+	if err := tok.Verify(ecKey.Public()); !errors.Is(err, jose.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	tok.Header.Alg = "none"
+	if err := tok.Verify(nil); !errors.Is(err, jose.ErrUnsupportedAlg) {
+		t.Errorf("expected ErrUnsupportedAlg, got %v", err)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, raw := range []string{"", "a.b", "!!!.e30.sig", "e30.!!!.sig", "e30.e30.!!!"} {
+		if _, err := jose.Parse(raw); !errors.Is(err, jose.ErrMalformed) {
+			t.Errorf("Parse(%q): expected ErrMalformed, got %v", raw, err)
+		}
+	}
+}
+
+func TestClaimsValidate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	claims := func(extra map[string]any) jose.Claims {
+		c := map[string]any{
+			"iss": "https://issuer.example",
+			"aud": []any{"iris", "other"},
+			"exp": now.Add(time.Minute).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		// Round-trip through a token so numbers are decoded like real input
+		raw, _ := josetest.Sign(jose.Header{Alg: jose.ES256}, c, mustEC(t))
+		tok, err := jose.Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		return tok.Claims
+	}
+	expect := jose.Expectations{Issuer: "https://issuer.example", Audience: "iris", Now: now, Skew: 30 * time.Second}
+
+	tests := []struct {
+		name   string
+		claims jose.Claims
+		want   error
+	}{
+		{"valid", claims(nil), nil},
+		{"string audience", claims(map[string]any{"aud": "iris"}), nil},
+		{"wrong issuer", claims(map[string]any{"iss": "https://evil.example"}), jose.ErrInvalidIssuer},
+		{"wrong audience", claims(map[string]any{"aud": "other"}), jose.ErrInvalidAudience},
+		{"expired", claims(map[string]any{"exp": now.Add(-time.Minute).Unix()}), jose.ErrExpired},
+		{"expired within skew", claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()}), nil},
+		{"not yet valid", claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}), jose.ErrNotYetValid},
+		{"missing exp", claims(map[string]any{"exp": nil}), jose.ErrMalformed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.claims.Validate(expect)
+			if tc.want == nil && err != nil {
+				t.Errorf("Validate() failed: %v", err)
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("Validate() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	// Unset expectations reject tokens instead of skipping the check
+	noIssuer, noAudience := expect, expect
+	noIssuer.Issuer, noAudience.Audience = "", ""
+	if err := claims(nil).Validate(noIssuer); !errors.Is(err, jose.ErrInvalidIssuer) {
+		t.Errorf("Validate() without issuer = %v, want %v", err, jose.ErrInvalidIssuer)
+	}
+	if err := claims(nil).Validate(noAudience); !errors.Is(err, jose.ErrInvalidAudience) {
+		t.Errorf("Validate() without audience = %v, want %v", err, jose.ErrInvalidAudience)
+	}
+}
+
+func mustEC(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,213 @@
+// Package metrics exposes counters and gauges in the Prometheus text
+// exposition format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Labels are the label names and values of one sample
+type Labels map[string]string
+
+// Sample is one value of a metric family
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Family is a named group of samples of the same type
+type Family interface {
+	Name() string
+	Help() string
+	// Type is "counter" or "gauge"
+	Type() string
+	Samples() []Sample
+}
+
+// Registry collects metric families and serves them over HTTP
+type Registry struct {
+	mu       sync.Mutex
+	families []Family
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds families to the registry
+func (r *Registry) Register(families ...Family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, families...)
+}
+
+// ServeHTTP writes every registered family in the text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := r.Write(w); err != nil {
+		log.Printf("writing metrics: %v", err)
+	}
+}
+
+// Write writes every registered family to w, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]Family(nil), r.families...)
+	r.mu.Unlock()
+	sort.SliceStable(families, func(i, j int) bool { return families[i].Name() < families[j].Name() })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name(), escapeHelp(f.Help()))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name(), f.Type())
+		samples := f.Samples()
+		sort.SliceStable(samples, func(i, j int) bool {
+			return formatLabels(samples[i].Labels) < formatLabels(samples[j].Labels)
+		})
+		for _, s := range samples {
+			fmt.Fprintf(bw, "%s%s %s\n", f.Name(), formatLabels(s.Labels), formatValue(s.Value))
+		}
+	}
+	return bw.Flush()
+}
+
+func formatLabels(l Labels) string {
+	if len(l) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(l[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a family of monotonically increasing values keyed by label
+// values
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	n      float64
+}
+
+// NewCounter returns a counter with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+}
+
+// Inc adds one to the counter with the given label values, which must
+// match the label names in number and order
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta to the counter with the given label values
+func (c *Counter) Add(delta float64, values ...string) {
+	if len(values) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", c.name, len(c.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: append([]string(nil), values...)}
+		c.values[key] = v
+	}
+	v.n += delta
+}
+
+// Value returns the current count for the given label values
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[strings.Join(values, "\xff")]; ok {
+		return v.n
+	}
+	return 0
+}
+
+func (c *Counter) Name() string { return c.name }
+func (c *Counter) Help() string { return c.help }
+func (c *Counter) Type() string { return "counter" }
+
+func (c *Counter) Samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Sample, 0, len(c.values))
+	for _, v := range c.values {
+		l := make(Labels, len(c.labels))
+		for i, name := range c.labels {
+			l[name] = v.labels[i]
+		}
+		out = append(out, Sample{Labels: l, Value: v.n})
+	}
+	return out
+}
+
+// GaugeFunc is a gauge family whose samples are computed on each scrape
+type GaugeFunc struct {
+	name, help string
+	fn         func() []Sample
+}
+
+// NewGaugeFunc returns a gauge that calls fn for its samples
+func NewGaugeFunc(name, help string, fn func() []Sample) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+func (g *GaugeFunc) Name() string      { return g.name }
+func (g *GaugeFunc) Help() string      { return g.help }
+func (g *GaugeFunc) Type() string      { return "gauge" }
+func (g *GaugeFunc) Samples() []Sample { return g.fn() }
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryServeHTTP(t *testing.T) {
+	reg := NewRegistry()
+	c := NewCounter("requests_total", "Requests served.", "code")
+	c.Inc("200")
+	c.Inc("200")
+	c.Add(3, "500")
+	g := NewGaugeFunc("temperature", "Current\ntemperature.", func() []Sample {
+		return []Sample{{Labels: Labels{"room": `a"b\c`}, Value: 0.5}}
+	})
+	reg.Register(g, c)
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	want := strings.Join([]string{
+		"# HELP requests_total Requests served.",
+		"# TYPE requests_total counter",
+		`requests_total{code="200"} 2`,
+		`requests_total{code="500"} 3`,
+		`# HELP temperature Current\ntemperature.`,
+		"# TYPE temperature gauge",
+		`temperature{room="a\"b\\c"} 0.5`,
+		"",
+	}, "\n")
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCounterValue(t *testing.T) {
+	c := NewCounter("hits_total", "Hits.", "page", "method")
+	c.Inc("/", "GET")
+	if got := c.Value("/", "GET"); got != 1 {
+		t.Errorf("Value() = %v, want 1", got)
+	}
+	if got := c.Value("/", "POST"); got != 0 {
+		t.Errorf("Value() = %v, want 0", got)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{1, "1"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
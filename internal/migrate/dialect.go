@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// SQLite locks by opening an IMMEDIATE transaction, which takes the
+// database write lock. A second instance blocks (up to its busy_timeout)
+// until the first commits, and then finds the migrations already applied.
+// Since SQLite DDL is transactional the whole run is atomic
+type SQLite struct{}
+
+func (SQLite) Placeholder(int) string { return "?" }
+
+func (SQLite) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	return err
+}
+
+func (SQLite) Unlock(ctx context.Context, conn *sql.Conn, failed bool) error {
+	stmt := "COMMIT"
+	if failed {
+		stmt = "ROLLBACK"
+	}
+	_, err := conn.ExecContext(ctx, stmt)
+	return err
+}
+
+func (SQLite) TxPerMigration() bool { return false }
+
+// postgresLockID is the advisory lock key shared by every instance
+// migrating the same database. It is arbitrary but must never change
+const postgresLockID int64 = 0x1415_7765_6273_7276
+
+// Postgres serializes instances with a session-level advisory lock and
+// runs each migration in its own transaction, so a failure leaves the
+// schema at the last migration that succeeded
+type Postgres struct{}
+
+func (Postgres) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (Postgres) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockID)
+	return err
+}
+
+func (Postgres) Unlock(ctx context.Context, conn *sql.Conn, _ bool) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockID)
+	return err
+}
+
+func (Postgres) TxPerMigration() bool { return true }
@@ -0,0 +1,288 @@
+// Package migrate applies numbered SQL migrations embedded in the binary
+// and records them in a schema_migrations table
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is a pair of up/down scripts sharing a version number
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one migration in the output of Migrator.Status
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Missing is set for versions recorded in the database that have no
+	// matching migration file, e.g. after a downgrade of the binary
+	Missing bool
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in dir of fsys. Files are named
+// NNNN_description.up.sql and NNNN_description.down.sql
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		if version == 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", e.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %q: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Dialect captures what differs between databases when migrating
+type Dialect interface {
+	// Placeholder returns the bind parameter syntax for the n-th (1-based)
+	// argument
+	Placeholder(n int) string
+	// Lock acquires an exclusive migration lock on conn, waiting for other
+	// instances to finish. It is held until Unlock
+	Lock(ctx context.Context, conn *sql.Conn) error
+	// Unlock releases the lock. failed reports whether the run failed
+	Unlock(ctx context.Context, conn *sql.Conn, failed bool) error
+	// TxPerMigration reports whether each migration should be wrapped in
+	// its own transaction. Dialects whose lock is itself a transaction
+	// return false
+	TxPerMigration() bool
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+	now        func() time.Time
+}
+
+// New returns a Migrator for db
+func New(db *sql.DB, dialect Dialect, migrations []Migration) *Migrator {
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, now: time.Now}
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT    NOT NULL,
+	applied_at BIGINT  NOT NULL
+)`
+
+// withLock runs fn on a dedicated connection while holding the migration
+// lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.dialect.Lock(ctx, conn); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if unlockErr := m.dialect.Unlock(context.WithoutCancel(ctx), conn, err != nil); unlockErr != nil && err == nil {
+			err = fmt.Errorf("releasing migration lock: %w", unlockErr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at int64
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("reading schema_migrations: %w", err)
+		}
+		applied[version] = time.Unix(0, at).UTC()
+	}
+	return applied, rows.Err()
+}
+
+// Up applies all pending migrations in order and returns their versions
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var done []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			record := fmt.Sprintf(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (%s, %s, %s)`,
+				m.dialect.Placeholder(1), m.dialect.Placeholder(2), m.dialect.Placeholder(3))
+			err := m.run(ctx, conn, mig.Up, record, mig.Version, mig.Name, m.now().UnixNano())
+			if err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig.Version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the most recently applied steps migrations and returns
+// their versions
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var done []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+			}
+			record := fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %s`, m.dialect.Placeholder(1))
+			if err := m.run(ctx, conn, mig.Down, record, mig.Version); err != nil {
+				return fmt.Errorf("rolling back migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig.Version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// run executes a migration script followed by its bookkeeping statement,
+// atomically when the dialect allows it
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	if !m.dialect.TxPerMigration() {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		known := make(map[int]bool)
+		for _, mig := range m.migrations {
+			known[mig.Version] = true
+			at, ok := applied[mig.Version]
+			out = append(out, Status{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at})
+		}
+		for version, at := range applied {
+			if !known[version] {
+				out = append(out, Status{Version: version, Applied: true, AppliedAt: at, Missing: true})
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+		return nil
+	})
+	return out, err
+}
+
+// ErrPending is returned by CheckCurrent when migrations are outstanding
+var ErrPending = errors.New("database schema has pending migrations")
+
+// CheckCurrent returns ErrPending unless every migration has been applied
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			return fmt.Errorf("%w: %d_%s", ErrPending, s.Version, s.Name)
+		}
+	}
+	return nil
+}
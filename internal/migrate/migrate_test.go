@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testFS = fstest.MapFS{
+	"m/0001_create_things.up.sql":   {Data: []byte(`CREATE TABLE things (id INTEGER PRIMARY KEY)`)},
+	"m/0001_create_things.down.sql": {Data: []byte(`DROP TABLE things`)},
+	"m/0002_add_name.up.sql":        {Data: []byte(`ALTER TABLE things ADD COLUMN name TEXT`)},
+	"m/0002_add_name.down.sql":      {Data: []byte(`ALTER TABLE things DROP COLUMN name`)},
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB) *Migrator {
+	t.Helper()
+	migrations, err := Load(testFS, "m")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return New(db, SQLite{}, migrations)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFS, "m")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "add_name" {
+		t.Errorf("Load() = %+v", migrations)
+	}
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"bad name", "m/create.sql"},
+		{"zero version", "m/0000_zero.up.sql"},
+		{"conflicting names", "m/0001_other.down.sql"},
+		{"down without up", "m/0003_orphan.down.sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"m/0001_create_things.up.sql": testFS["m/0001_create_things.up.sql"]}
+			fsys[tt.file] = &fstest.MapFile{Data: []byte("SELECT 1")}
+			if _, err := Load(fsys, "m"); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+	m := newMigrator(t, db)
+
+	if err := m.CheckCurrent(ctx); !errors.Is(err, ErrPending) {
+		t.Fatalf("CheckCurrent() on empty database = %v, want ErrPending", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("Up() applied %v, want [1 2]", applied)
+	}
+	if _, err := db.Exec(`INSERT INTO things (name) VALUES ('a')`); err != nil {
+		t.Errorf("schema not applied: %v", err)
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second Up() = %v, %v; want nothing applied", applied, err)
+	}
+	if err := m.CheckCurrent(ctx); err != nil {
+		t.Errorf("CheckCurrent() after Up = %v", err)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0] != 2 {
+		t.Fatalf("Down(1) = %v, %v; want [2]", reverted, err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("Status() = %+v, want 1 applied and 2 pending", statuses)
+	}
+	if statuses[0].AppliedAt.IsZero() {
+		t.Error("AppliedAt not recorded")
+	}
+
+	reverted, err = m.Down(ctx, 5)
+	if err != nil || len(reverted) != 1 {
+		t.Errorf("Down(5) = %v, %v; want [1]", reverted, err)
+	}
+	if _, err := db.Exec(`SELECT * FROM things`); err == nil {
+		t.Error("table still exists after rolling back everything")
+	}
+}
+
+func TestStatusReportsMissingFiles(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+	if _, err := newMigrator(t, db).Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	older := New(db, SQLite{}, []Migration{{Version: 1, Name: "create_things", Up: "SELECT 1"}})
+	statuses, err := older.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[1].Missing || statuses[1].Version != 2 {
+		t.Errorf("Status() = %+v, want version 2 reported missing", statuses)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+	m := New(db, SQLite{}, []Migration{
+		{Version: 1, Name: "good", Up: `CREATE TABLE good (id INTEGER)`},
+		{Version: 2, Name: "bad", Up: `CREATE TABLE nonsense (`},
+	})
+
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("expected error from broken migration")
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d recorded as applied after failed run", s.Version)
+		}
+	}
+}
+
+func TestConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	migrators := []*Migrator{newMigrator(t, openDB(t, path)), newMigrator(t, openDB(t, path))}
+
+	var wg sync.WaitGroup
+	results := make([][]int, len(migrators))
+	errs := make([]error, len(migrators))
+	for i, m := range migrators {
+		wg.Add(1)
+		go func(i int, m *Migrator) {
+			defer wg.Done()
+			results[i], errs[i] = m.Up(ctx)
+		}(i, m)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range migrators {
+		if errs[i] != nil {
+			t.Errorf("Up() #%d failed: %v", i, errs[i])
+		}
+		total += len(results[i])
+	}
+	if total != 2 {
+		t.Errorf("migrations applied %d times in total, want each exactly once", total)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	if got := (SQLite{}).Placeholder(3); got != "?" {
+		t.Errorf("SQLite placeholder = %q, want ?", got)
+	}
+	if got := (Postgres{}).Placeholder(3); got != "$3" {
+		t.Errorf("Postgres placeholder = %q, want $3", got)
+	}
+}
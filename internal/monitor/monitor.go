@@ -0,0 +1,270 @@
+// Package monitor records served predictions and compares their inputs
+// with the distribution each model was trained on
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/metrics"
+)
+
+// PSI thresholds commonly used to read a population stability index
+const (
+	PSIModerate    = 0.1
+	PSISignificant = 0.25
+)
+
+// minProportion stands in for empty bins so that the PSI stays finite
+const minProportion = 1e-4
+
+// Record is one line of the prediction log
+type Record struct {
+	Time          time.Time          `json:"time"`
+	Model         string             `json:"model"`
+	Features      iris.Features      `json:"features"`
+	Species       string             `json:"species"`
+	Probabilities map[string]float64 `json:"probabilities"`
+}
+
+// Monitor keeps running statistics of the inputs served by each model and
+// logs a sample of the predictions. A nil Monitor discards observations,
+// so callers do not need to check whether monitoring is enabled
+type Monitor struct {
+	sampleRate  float64
+	rand        func() float64
+	now         func() time.Time
+	predictions *metrics.Counter
+
+	mu     sync.Mutex
+	file   *os.File
+	models map[string]*modelStats
+}
+
+type modelStats struct {
+	training *iris.Distribution
+	count    int64
+	features []featureStats
+}
+
+// featureStats counts live inputs in the training bins and tracks their
+// mean and variance with Welford's algorithm
+type featureStats struct {
+	counts   []int64
+	mean, m2 float64
+}
+
+// New returns a Monitor logging to cfg.LogPath, if set
+func New(cfg config.MonitorConfig) (*Monitor, error) {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, errors.New("monitoring sample_rate must be between 0 and 1")
+	}
+	m := &Monitor{
+		sampleRate:  cfg.SampleRate,
+		rand:        rand.Float64,
+		now:         time.Now,
+		predictions: metrics.NewCounter("iris_predictions_total", "Predictions served, by model and predicted species.", "model", "species"),
+		models:      make(map[string]*modelStats),
+	}
+	if cfg.LogPath != "" {
+		f, err := os.OpenFile(cfg.LogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("opening prediction log: %w", err)
+		}
+		m.file = f
+	}
+	return m, nil
+}
+
+// Observe records a prediction served by model e
+func (m *Monitor) Observe(e *iris.Entry, f iris.Features, p iris.Prediction) error {
+	if m == nil {
+		return nil
+	}
+	m.predictions.Inc(e.Version, p.Species)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.models[e.Version]
+	if !ok {
+		st = newModelStats(e.Training)
+		m.models[e.Version] = st
+	}
+	st.count++
+	for i, x := range f.Vector() {
+		fs := &st.features[i]
+		if fs.counts != nil {
+			fs.counts[st.training.Features[i].Bin(x)]++
+		}
+		delta := x - fs.mean
+		fs.mean += delta / float64(st.count)
+		fs.m2 += delta * (x - fs.mean)
+	}
+
+	if m.file == nil || m.rand() >= m.sampleRate {
+		return nil
+	}
+	line, err := json.Marshal(Record{
+		Time:          m.now().UTC(),
+		Model:         e.Version,
+		Features:      f,
+		Species:       p.Species,
+		Probabilities: p.Probabilities,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding prediction: %w", err)
+	}
+	if _, err := m.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing prediction log: %w", err)
+	}
+	return nil
+}
+
+func newModelStats(training *iris.Distribution) *modelStats {
+	st := &modelStats{training: training, features: make([]featureStats, len(iris.FeatureNames))}
+	if training != nil {
+		for i, fd := range training.Features {
+			st.features[i].counts = make([]int64, len(fd.Proportions))
+		}
+	}
+	return st
+}
+
+// FeatureDrift compares the live inputs of one feature with its training
+// distribution
+type FeatureDrift struct {
+	Name string
+	// PSI is the population stability index of the live inputs
+	PSI float64
+	// Mean and Std describe the live inputs
+	Mean, Std                 float64
+	TrainingMean, TrainingStd float64
+	// Edges are the training bin cut points; Expected and Actual are the
+	// training and live proportions per bin
+	Edges            []float64
+	Expected, Actual []float64
+}
+
+// Status classifies the PSI as "stable", "moderate" or "significant"
+func (d FeatureDrift) Status() string {
+	switch {
+	case d.PSI >= PSISignificant:
+		return "significant"
+	case d.PSI >= PSIModerate:
+		return "moderate"
+	}
+	return "stable"
+}
+
+// ModelDrift is the drift of every feature served by one model.
+// Features is empty for models without a training distribution
+type ModelDrift struct {
+	Version      string
+	Observations int64
+	Features     []FeatureDrift
+}
+
+// Snapshot returns the drift of each model that has served a prediction,
+// ordered by version
+func (m *Monitor) Snapshot() []ModelDrift {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]ModelDrift, 0, len(m.models))
+	for version, st := range m.models {
+		md := ModelDrift{Version: version, Observations: st.count}
+		if st.training != nil {
+			for i, fd := range st.training.Features {
+				md.Features = append(md.Features, st.drift(i, fd))
+			}
+		}
+		out = append(out, md)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+func (st *modelStats) drift(i int, fd iris.FeatureDistribution) FeatureDrift {
+	fs := st.features[i]
+	d := FeatureDrift{
+		Name:         fd.Name,
+		Mean:         fs.mean,
+		Std:          math.Sqrt(fs.m2 / float64(st.count)),
+		TrainingMean: fd.Mean,
+		TrainingStd:  fd.Std,
+		Edges:        fd.Edges,
+		Expected:     fd.Proportions,
+		Actual:       make([]float64, len(fs.counts)),
+	}
+	for b, c := range fs.counts {
+		d.Actual[b] = float64(c) / float64(st.count)
+	}
+	d.PSI = PSI(d.Expected, d.Actual)
+	return d
+}
+
+// PSI returns the population stability index of actual against expected
+// bin proportions
+func PSI(expected, actual []float64) float64 {
+	var psi float64
+	for i := range expected {
+		e := math.Max(expected[i], minProportion)
+		a := math.Max(actual[i], minProportion)
+		psi += (a - e) * math.Log(a/e)
+	}
+	return psi
+}
+
+// Families returns the metric families exported by the monitor
+func (m *Monitor) Families() []metrics.Family {
+	return []metrics.Family{
+		m.predictions,
+		metrics.NewGaugeFunc("iris_feature_drift_psi", "Population stability index of live inputs against the training data.", func() []metrics.Sample {
+			return m.featureSamples(func(d FeatureDrift) float64 { return d.PSI })
+		}),
+		metrics.NewGaugeFunc("iris_feature_mean", "Mean of live inputs in centimetres.", func() []metrics.Sample {
+			return m.featureSamples(func(d FeatureDrift) float64 { return d.Mean })
+		}),
+	}
+}
+
+func (m *Monitor) featureSamples(value func(FeatureDrift) float64) []metrics.Sample {
+	var out []metrics.Sample
+	for _, md := range m.Snapshot() {
+		for _, d := range md.Features {
+			out = append(out, metrics.Sample{
+				Labels: metrics.Labels{"model": md.Version, "feature": d.Name},
+				Value:  value(d),
+			})
+		}
+	}
+	return out
+}
+
+// Close closes the prediction log
+func (m *Monitor) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file = nil
+	return err
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/iris"
+	"github.com/Elenetta17/iris-web-service/internal/metrics"
+)
+
+func observeAll(t *testing.T, m *Monitor, e *iris.Entry, samples []iris.Sample) {
+	t.Helper()
+	for _, s := range samples {
+		if err := m.Observe(e, s.Features, e.Classifier.Predict(s.Features)); err != nil {
+			t.Fatalf("Observe() failed: %v", err)
+		}
+	}
+}
+
+func TestMonitorNoDriftOnTrainingData(t *testing.T) {
+	m, err := New(config.MonitorConfig{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	e := iris.BundledEntry()
+	observeAll(t, m, &e, iris.Dataset())
+
+	snap := m.Snapshot()
+	if len(snap) != 1 || snap[0].Version != iris.BundledVersion || snap[0].Observations != 150 {
+		t.Fatalf("Snapshot() = %+v", snap)
+	}
+	for _, d := range snap[0].Features {
+		if d.PSI > 1e-9 {
+			t.Errorf("%s: PSI = %v, want 0", d.Name, d.PSI)
+		}
+		if math.Abs(d.Mean-d.TrainingMean) > 1e-9 || math.Abs(d.Std-d.TrainingStd) > 1e-9 {
+			t.Errorf("%s: live mean/std %v/%v, want %v/%v", d.Name, d.Mean, d.Std, d.TrainingMean, d.TrainingStd)
+		}
+		if d.Status() != "stable" {
+			t.Errorf("%s: Status() = %q, want stable", d.Name, d.Status())
+		}
+	}
+}
+
+func TestMonitorDetectsDrift(t *testing.T) {
+	m, _ := New(config.MonitorConfig{})
+	e := iris.BundledEntry()
+	var setosa []iris.Sample
+	for _, s := range iris.Dataset() {
+		if s.Species == iris.Setosa {
+			setosa = append(setosa, s)
+		}
+	}
+	observeAll(t, m, &e, setosa)
+
+	for _, d := range m.Snapshot()[0].Features {
+		if d.Name == "petal_length" && d.Status() != "significant" {
+			t.Errorf("petal_length: PSI = %v, want significant drift", d.PSI)
+		}
+	}
+}
+
+func TestMonitorWithoutTrainingDistribution(t *testing.T) {
+	m, _ := New(config.MonitorConfig{})
+	e := iris.BundledEntry()
+	e.Training = nil
+	observeAll(t, m, &e, iris.Dataset()[:3])
+
+	snap := m.Snapshot()
+	if len(snap) != 1 || snap[0].Observations != 3 || len(snap[0].Features) != 0 {
+		t.Errorf("Snapshot() = %+v", snap)
+	}
+}
+
+func TestMonitorSampledLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "predictions.jsonl")
+	m, err := New(config.MonitorConfig{SampleRate: 0.5, LogPath: path})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	rolls := []float64{0.1, 0.9, 0.4, 0.5}
+	m.rand = func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+	e := iris.BundledEntry()
+	observeAll(t, m, &e, iris.Dataset()[:4])
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 2 {
+		t.Fatalf("logged %d predictions, want 2", len(records))
+	}
+	if records[0].Model != iris.BundledVersion || records[0].Species != iris.Setosa || records[0].Features != iris.Dataset()[0].Features {
+		t.Errorf("record = %+v", records[0])
+	}
+}
+
+func TestNewRejectsSampleRate(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1.5} {
+		if _, err := New(config.MonitorConfig{SampleRate: rate}); err == nil {
+			t.Errorf("New(sample_rate=%v) succeeded, want error", rate)
+		}
+	}
+}
+
+func TestNilMonitor(t *testing.T) {
+	var m *Monitor
+	e := iris.BundledEntry()
+	if err := m.Observe(&e, iris.Features{}, iris.Prediction{}); err != nil {
+		t.Errorf("Observe() = %v", err)
+	}
+	if m.Snapshot() != nil {
+		t.Error("Snapshot() of nil monitor is not empty")
+	}
+}
+
+func TestPSI(t *testing.T) {
+	tests := []struct {
+		name             string
+		expected, actual []float64
+		want             float64
+	}{
+		{"identical", []float64{0.5, 0.5}, []float64{0.5, 0.5}, 0},
+		{"shifted", []float64{0.5, 0.5}, []float64{0.25, 0.75}, 0.25*math.Log(0.75/0.5) - 0.25*math.Log(0.25/0.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PSI(tt.expected, tt.actual); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("PSI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := PSI([]float64{1, 0}, []float64{0, 1}); math.IsInf(got, 0) || math.IsNaN(got) {
+		t.Errorf("PSI() with empty bins = %v, want finite", got)
+	}
+}
+
+func TestMonitorMetrics(t *testing.T) {
+	m, _ := New(config.MonitorConfig{})
+	e := iris.BundledEntry()
+	observeAll(t, m, &e, iris.Dataset()[:2])
+	reg := metrics.NewRegistry()
+	reg.Register(m.Families()...)
+
+	var b strings.Builder
+	if err := reg.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`iris_predictions_total{model="bundled-knn",species="setosa"} 2`,
+		`iris_feature_drift_psi{feature="petal_width",model="bundled-knn"} `,
+		`iris_feature_mean{feature="sepal_length",model="bundled-knn"} 5`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+}
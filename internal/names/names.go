@@ -0,0 +1,139 @@
+// Package names validates and normalizes the names people type into the
+// greeting form
+package names
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+// DefaultMaxLength is the limit of a nil Validator, matching the default
+// configuration
+const DefaultMaxLength = 64
+
+// Error is a rejected name. Key names its message in the i18n catalogs
+type Error struct {
+	Key  string
+	Args []any
+}
+
+func (e *Error) Error() string {
+	return e.Localize(i18n.Default())
+}
+
+// Localize returns the message of e in the language of c
+func (e *Error) Localize(c *i18n.Catalog) string {
+	return c.T(e.Key, e.Args...)
+}
+
+// Rule checks a name, possibly rewriting it. Rules run in order, each on
+// the output of the previous one
+type Rule func(name string) (string, error)
+
+// Validator runs a pipeline of rules. A nil Validator applies the default
+// rules with DefaultMaxLength
+type Validator struct {
+	rules []Rule
+}
+
+var defaultValidator = &Validator{rules: []Rule{Normalize, RejectControl, MaxLength(DefaultMaxLength)}}
+
+// New returns a Validator that normalizes names, rejects control and
+// bidirectional formatting characters, then applies the limits of cfg. A
+// zero MaxLength means DefaultMaxLength
+func New(cfg config.NameConfig) (*Validator, error) {
+	maxLength := cfg.MaxLength
+	switch {
+	case maxLength == 0:
+		maxLength = DefaultMaxLength
+	case maxLength < 0:
+		return nil, fmt.Errorf("names: max_length must be positive, got %d", maxLength)
+	}
+	v := &Validator{rules: []Rule{Normalize, RejectControl, MaxLength(maxLength)}}
+	if len(cfg.Scripts) > 0 {
+		rule, err := Scripts(cfg.Scripts...)
+		if err != nil {
+			return nil, err
+		}
+		v.rules = append(v.rules, rule)
+	}
+	return v, nil
+}
+
+// Validate returns the normalized name, or an *Error saying why it was
+// rejected. A blank name is returned as ""
+func (v *Validator) Validate(name string) (string, error) {
+	if v == nil {
+		v = defaultValidator
+	}
+	for _, rule := range v.rules {
+		var err error
+		if name, err = rule(name); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// Normalize trims surrounding white space and converts name to NFC, so
+// that "é" typed as one or two code points compares equal
+func Normalize(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", &Error{Key: "names.invalid"}
+	}
+	return norm.NFC.String(strings.TrimSpace(name)), nil
+}
+
+// RejectControl rejects control characters and the bidirectional
+// formatting characters that can make a name display as something else
+func RejectControl(name string) (string, error) {
+	for _, r := range name {
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return "", &Error{Key: "names.control"}
+		}
+	}
+	return name, nil
+}
+
+// MaxLength rejects names longer than n characters
+func MaxLength(n int) Rule {
+	return func(name string) (string, error) {
+		if utf8.RuneCountInString(name) > n {
+			return "", &Error{Key: "names.too_long", Args: []any{n}}
+		}
+		return name, nil
+	}
+}
+
+// Scripts rejects names with letters outside the named Unicode scripts.
+// Marks, digits and punctuation are not restricted
+func Scripts(scripts ...string) (Rule, error) {
+	tables := make([]*unicode.RangeTable, len(scripts))
+	for i, s := range scripts {
+		t, ok := unicode.Scripts[s]
+		if !ok {
+			return nil, fmt.Errorf("names: unknown script %q", s)
+		}
+		tables[i] = t
+	}
+	sorted := append([]string(nil), scripts...)
+	sort.Strings(sorted)
+	allowed := strings.Join(sorted, ", ")
+
+	return func(name string) (string, error) {
+		for _, r := range name {
+			if unicode.IsLetter(r) && !unicode.In(r, tables...) {
+				return "", &Error{Key: "names.script", Args: []any{allowed}}
+			}
+		}
+		return name, nil
+	}, nil
+}
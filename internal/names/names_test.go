@@ -0,0 +1,84 @@
+package names
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/i18n"
+)
+
+func TestValidate(t *testing.T) {
+	latin, err := New(config.NameConfig{MaxLength: 5, Scripts: []string{"Latin"}})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		v       *Validator
+		input   string
+		want    string
+		wantKey string
+	}{
+		{"trimmed", nil, "  Ada \n", "Ada", ""},
+		{"blank", nil, " \t ", "", ""},
+		{"composed", nil, "José", "José", ""},
+		{"emoji", nil, "Ada 🌸", "Ada 🌸", ""},
+		{"invalid utf-8", nil, "Ada\xff", "", "names.invalid"},
+		{"control", nil, "Ada\x00Lovelace", "", "names.control"},
+		{"newline inside", nil, "Ada\nLovelace", "", "names.control"},
+		{"bidi override", nil, "Ada\u202eecalevol", "", "names.control"},
+		{"bidi isolate", nil, "\u2067Ada", "", "names.control"},
+		{"too long", nil, strings.Repeat("a", DefaultMaxLength+1), "", "names.too_long"},
+		{"runes not bytes", latin, "Zoë", "Zoë", ""},
+		{"length after normalization", latin, "José", "José", ""},
+		{"configured length", latin, "Adelaide", "", "names.too_long"},
+		{"other script", latin, "Ζωή", "", "names.script"},
+		{"digits allowed", latin, "Ada 2", "Ada 2", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.v.Validate(tc.input)
+			if tc.wantKey == "" {
+				if err != nil {
+					t.Fatalf("Validate(%q) failed: %v", tc.input, err)
+				}
+				if got != tc.want {
+					t.Errorf("Validate(%q) = %q, want %q", tc.input, got, tc.want)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Validate(%q) error = %v, want *Error", tc.input, err)
+			}
+			if e.Key != tc.wantKey {
+				t.Errorf("Validate(%q) key = %q, want %q", tc.input, e.Key, tc.wantKey)
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for _, cfg := range []config.NameConfig{
+		{MaxLength: -1},
+		{MaxLength: 10, Scripts: []string{"Klingon"}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestErrorLocalize(t *testing.T) {
+	e := &Error{Key: "names.too_long", Args: []any{1}}
+	if got, want := e.Error(), "Use at most 1 character."; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := e.Localize(i18n.Lookup("fr")), "Utilisez au plus 1 caractère."; got != want {
+		t.Errorf("Localize(fr) = %q, want %q", got, want)
+	}
+}
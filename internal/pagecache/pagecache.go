@@ -0,0 +1,290 @@
+// Package pagecache keeps rendered pages in an in-memory LRU cache and
+// answers conditional requests for them with 304 Not Modified
+package pagecache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/metrics"
+)
+
+// Cache results counted by iris_page_cache_requests_total
+const (
+	ResultHit    = "hit"
+	ResultMiss   = "miss"
+	ResultBypass = "bypass"
+)
+
+// DefaultCacheControl makes clients revalidate cached pages, which is
+// cheap thanks to ETags
+const DefaultCacheControl = "no-cache"
+
+// storedHeaders are the response headers kept with a cached page. Others,
+// such as X-Request-ID and Set-Cookie, belong to a single response
+var storedHeaders = []string{
+	"Content-Disposition",
+	"Content-Language",
+	"Content-Type",
+	"X-Content-Type-Options",
+}
+
+// VaryFunc names the variant of a page a request gets, such as its
+// language. Requests for personalized pages return false and are neither
+// cached nor given an ETag
+type VaryFunc func(r *http.Request) (variant string, ok bool)
+
+type entry struct {
+	key      string
+	header   http.Header
+	body     []byte
+	etag     string
+	modified time.Time
+	expires  time.Time
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.body))
+}
+
+// Cache is an LRU cache of rendered pages, bounded in entries and bytes.
+// It is safe for concurrent use. A nil Cache stores nothing but still
+// answers conditional requests using ETags
+type Cache struct {
+	maxBytes   int64
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	lru   *list.List // of *entry, most recently used first
+	items map[string]*list.Element
+	bytes int64
+
+	requests  *metrics.Counter
+	evictions *metrics.Counter
+}
+
+// New returns a Cache with the limits of cfg, or nil if cfg.MaxBytes is
+// zero
+func New(cfg config.PageCacheConfig) *Cache {
+	if cfg.MaxBytes <= 0 {
+		return nil
+	}
+	return &Cache{
+		maxBytes:   cfg.MaxBytes,
+		maxEntries: cfg.MaxEntries,
+		ttl:        cfg.TTL,
+		now:        time.Now,
+		lru:        list.New(),
+		items:      make(map[string]*list.Element),
+		requests:   metrics.NewCounter("iris_page_cache_requests_total", "Requests for cacheable pages, by result.", "result"),
+		evictions:  metrics.NewCounter("iris_page_cache_evictions_total", "Pages evicted from the cache to respect its limits."),
+	}
+}
+
+// Families returns the metric families exported by the cache
+func (c *Cache) Families() []metrics.Family {
+	if c == nil {
+		return nil
+	}
+	return []metrics.Family{
+		c.requests,
+		c.evictions,
+		metrics.NewGaugeFunc("iris_page_cache_entries", "Pages in the cache.", func() []metrics.Sample {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return []metrics.Sample{{Value: float64(c.lru.Len())}}
+		}),
+		metrics.NewGaugeFunc("iris_page_cache_bytes", "Size of the cached pages.", func() []metrics.Sample {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return []metrics.Sample{{Value: float64(c.bytes)}}
+		}),
+	}
+}
+
+// Handler serves GET and HEAD requests for next from the cache, keyed by
+// path, query and the variant named by vary. Successful responses get an
+// ETag, Last-Modified and cacheControl
+func (c *Cache) Handler(cacheControl string, vary VaryFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		variant, ok := vary(r)
+		if !ok {
+			c.count(ResultBypass)
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.URL.Path + "?" + r.URL.RawQuery + "\x00" + variant
+
+		if e := c.get(key); e != nil {
+			c.count(ResultHit)
+			for _, name := range storedHeaders {
+				if v, ok := e.header[name]; ok {
+					w.Header()[name] = v
+				}
+			}
+			serve(w, r, e, cacheControl)
+			return
+		}
+		c.count(ResultMiss)
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status != http.StatusOK || w.Header().Get("Set-Cookie") != "" {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(rec.body.Bytes())
+		now := time.Now()
+		if c != nil {
+			now = c.now()
+		}
+		e := &entry{
+			key:      key,
+			header:   make(http.Header),
+			body:     rec.body.Bytes(),
+			etag:     `"` + hex.EncodeToString(sum[:8]) + `"`,
+			modified: now,
+		}
+		for _, name := range storedHeaders {
+			if v, ok := w.Header()[name]; ok {
+				e.header[name] = v
+			}
+		}
+		c.add(e)
+		serve(w, r, e, cacheControl)
+	})
+}
+
+func (c *Cache) count(result string) {
+	if c != nil {
+		c.requests.Inc(result)
+	}
+}
+
+func (c *Cache) get(key string) *entry {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if c.ttl > 0 && !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// add stores e, evicting the least recently used pages to make room.
+// Pages larger than the whole cache are not stored
+func (c *Cache) add(e *entry) {
+	if c == nil || e.size() > c.maxBytes {
+		return
+	}
+	e.expires = e.modified.Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		c.remove(el)
+	}
+	c.items[e.key] = c.lru.PushFront(e)
+	c.bytes += e.size()
+	for c.bytes > c.maxBytes || (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) {
+		c.remove(c.lru.Back())
+		c.evictions.Inc()
+	}
+}
+
+// remove drops el. Callers must hold c.mu
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size()
+}
+
+// serve writes e, or 304 Not Modified if the client has it already
+func serve(w http.ResponseWriter, r *http.Request, e *entry, cacheControl string) {
+	h := w.Header()
+	h.Set("ETag", e.etag)
+	h.Set("Last-Modified", e.modified.UTC().Format(http.TimeFormat))
+	if cacheControl != "" {
+		h.Set("Cache-Control", cacheControl)
+	}
+	if notModified(r, e) {
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(e.body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(e.body)
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there
+// is no If-None-Match, as RFC 9110 requires
+func notModified(r *http.Request, e *entry) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == e.etag {
+				return true
+			}
+		}
+		return false
+	}
+	t, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !e.modified.Truncate(time.Second).After(t)
+}
+
+// recorder buffers a response so that it can be hashed and cached
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
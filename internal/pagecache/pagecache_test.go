@@ -0,0 +1,259 @@
+package pagecache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// counting renders a page that names its variant and counts renders
+type counting struct {
+	renders int
+	status  int
+}
+
+func (p *counting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.renders++
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Request-ID", fmt.Sprint("req-", p.renders))
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+	}
+	fmt.Fprintf(w, "<p>%s %s</p>", r.URL.Path, r.Header.Get("Accept-Language"))
+}
+
+func byLanguage(r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	return r.Header.Get("Accept-Language"), true
+}
+
+func get(h http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func newTestCache(cfg config.PageCacheConfig) (*Cache, *time.Time) {
+	c := New(cfg)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCacheHitsAndVariants(t *testing.T) {
+	c, _ := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20})
+	page := &counting{}
+	h := c.Handler("public, max-age=60", byLanguage, page)
+
+	first := get(h, "/", "Accept-Language", "fr")
+	second := get(h, "/", "Accept-Language", "fr")
+	if page.renders != 1 {
+		t.Errorf("renders = %d, want 1", page.renders)
+	}
+	if first.Body.String() != second.Body.String() || first.Header().Get("ETag") != second.Header().Get("ETag") {
+		t.Error("cached response differs from the rendered one")
+	}
+	for _, rr := range []*httptest.ResponseRecorder{first, second} {
+		if got, want := rr.Header().Get("Cache-Control"), "public, max-age=60"; got != want {
+			t.Errorf("Cache-Control = %q, want %q", got, want)
+		}
+		if got := rr.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("Content-Type = %q", got)
+		}
+	}
+	if got := second.Header().Get("X-Request-ID"); got != "" {
+		t.Errorf("per-response header replayed from the cache: X-Request-ID = %q", got)
+	}
+
+	get(h, "/", "Accept-Language", "de")
+	get(h, "/?page=2", "Accept-Language", "fr")
+	if page.renders != 3 {
+		t.Errorf("renders = %d, want 3 for two new variants", page.renders)
+	}
+
+	get(h, "/", "Accept-Language", "fr", "Authorization", "Bearer x")
+	if page.renders != 4 {
+		t.Errorf("renders = %d, want personalized request to bypass the cache", page.renders)
+	}
+
+	for result, want := range map[string]float64{ResultHit: 1, ResultMiss: 3, ResultBypass: 1} {
+		if got := c.requests.Value(result); got != want {
+			t.Errorf("%s count = %v, want %v", result, got, want)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	c, now := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20})
+	h := c.Handler(DefaultCacheControl, byLanguage, &counting{})
+
+	rr := get(h, "/")
+	etag, modified := rr.Header().Get("ETag"), rr.Header().Get("Last-Modified")
+	if etag == "" || modified != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, modified)
+	}
+
+	*now = now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		headers []string
+		status  int
+	}{
+		{"matching etag", []string{"If-None-Match", etag}, http.StatusNotModified},
+		{"weak etag from compression", []string{"If-None-Match", "W/" + etag}, http.StatusNotModified},
+		{"etag list", []string{"If-None-Match", `"other", ` + etag}, http.StatusNotModified},
+		{"stale etag", []string{"If-None-Match", `"other"`}, http.StatusOK},
+		{"not modified since", []string{"If-Modified-Since", modified}, http.StatusNotModified},
+		{"modified since", []string{"If-Modified-Since", "Wed, 01 May 2024 11:00:00 GMT"}, http.StatusOK},
+		{"etag wins over date", []string{"If-None-Match", `"other"`, "If-Modified-Since", modified}, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := get(h, "/", tc.headers...)
+			if rr.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.status)
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 with body %q", rr.Body.String())
+			}
+			if got := rr.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+		})
+	}
+}
+
+func TestNilCacheStillRevalidates(t *testing.T) {
+	var c *Cache
+	page := &counting{}
+	h := c.Handler(DefaultCacheControl, byLanguage, page)
+
+	etag := get(h, "/").Header().Get("ETag")
+	rr := get(h, "/", "If-None-Match", etag)
+	if got, want := rr.Code, http.StatusNotModified; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if page.renders != 2 {
+		t.Errorf("renders = %d, want 2 without a cache", page.renders)
+	}
+	if c.Families() != nil {
+		t.Error("nil cache exports metrics")
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	c, _ := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20})
+	page := &counting{status: http.StatusBadRequest}
+	h := c.Handler(DefaultCacheControl, byLanguage, page)
+
+	for i := 0; i < 2; i++ {
+		rr := get(h, "/dataset?x=bogus")
+		if rr.Code != http.StatusBadRequest || rr.Header().Get("ETag") != "" {
+			t.Errorf("status = %d, ETag = %q", rr.Code, rr.Header().Get("ETag"))
+		}
+		if !strings.Contains(rr.Body.String(), "/dataset") {
+			t.Errorf("body = %q", rr.Body.String())
+		}
+	}
+	if page.renders != 2 {
+		t.Errorf("renders = %d, want 2", page.renders)
+	}
+}
+
+func TestCookiesAreNotCached(t *testing.T) {
+	c, _ := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20})
+	renders := 0
+	h := c.Handler(DefaultCacheControl, byLanguage, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renders++
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret"})
+		io.WriteString(w, "page")
+	}))
+
+	get(h, "/")
+	if rr := get(h, "/"); rr.Header().Get("Set-Cookie") == "" {
+		t.Error("cookie missing from second response")
+	}
+	if renders != 2 {
+		t.Errorf("renders = %d, want 2", renders)
+	}
+}
+
+func TestHead(t *testing.T) {
+	c, _ := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20})
+	h := c.Handler(DefaultCacheControl, byLanguage, &counting{})
+
+	req := httptest.NewRequest(http.MethodHead, "/", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 || rr.Header().Get("Content-Length") == "" {
+		t.Errorf("HEAD: status = %d, body = %q, Content-Length = %q", rr.Code, rr.Body.String(), rr.Header().Get("Content-Length"))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	c, _ := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20, MaxEntries: 2})
+	page := &counting{}
+	h := c.Handler(DefaultCacheControl, byLanguage, page)
+
+	get(h, "/p1")
+	get(h, "/p2")
+	get(h, "/p1") // p2 is now least recently used
+	get(h, "/p3")
+	if page.renders != 3 {
+		t.Fatalf("renders = %d, want 3", page.renders)
+	}
+	get(h, "/p1")
+	if page.renders != 3 {
+		t.Error("recently used page was evicted")
+	}
+	get(h, "/p2")
+	if page.renders != 4 {
+		t.Error("least recently used page was kept")
+	}
+	if got := c.evictions.Value(); got != 2 {
+		t.Errorf("evictions = %v, want 2", got)
+	}
+
+	// "/a" takes 14 bytes: its key "/a?\x00" and body "<p>/a </p>"
+	small, _ := newTestCache(config.PageCacheConfig{MaxBytes: 20})
+	h = small.Handler(DefaultCacheControl, byLanguage, page)
+	get(h, "/a")
+	get(h, "/b")
+	if small.bytes != 14 || small.lru.Len() != 1 {
+		t.Errorf("cache holds %d bytes in %d entries, limit is 20 bytes", small.bytes, small.lru.Len())
+	}
+	get(h, "/"+strings.Repeat("x", 100))
+	if small.bytes != 14 {
+		t.Errorf("oversized page stored: %d bytes", small.bytes)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c, now := newTestCache(config.PageCacheConfig{MaxBytes: 1 << 20, TTL: time.Minute})
+	page := &counting{}
+	h := c.Handler(DefaultCacheControl, byLanguage, page)
+
+	get(h, "/")
+	*now = now.Add(59 * time.Second)
+	get(h, "/")
+	if page.renders != 1 {
+		t.Errorf("renders = %d before expiry, want 1", page.renders)
+	}
+	*now = now.Add(time.Second)
+	get(h, "/")
+	if page.renders != 2 {
+		t.Errorf("renders = %d after expiry, want 2", page.renders)
+	}
+}
@@ -0,0 +1,100 @@
+// Package rbac decides whether a principal holds a permission based on the
+// roles declared in the configuration
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// Wildcard grants every permission when listed in a role
+const Wildcard = "*"
+
+// methods are the authentication methods an assignment can name
+var methods = []string{auth.MethodAPIKey, auth.MethodJWT, auth.MethodOIDC}
+
+// Policy maps roles to the permissions they grant
+type Policy struct {
+	roles       map[string]map[string]bool
+	assignments map[string][]string
+}
+
+// NewPolicy builds a Policy from cfg. Assignments are keyed by
+// "method:id", e.g. "api_key:reporting", so that identities from different
+// authentication methods cannot share roles, and must only reference
+// declared roles
+func NewPolicy(cfg config.RBACConfig) (*Policy, error) {
+	p := &Policy{
+		roles:       make(map[string]map[string]bool, len(cfg.Roles)),
+		assignments: cfg.Assignments,
+	}
+	for role, perms := range cfg.Roles {
+		set := make(map[string]bool, len(perms))
+		for _, perm := range perms {
+			set[perm] = true
+		}
+		p.roles[role] = set
+	}
+
+	for principal, roles := range cfg.Assignments {
+		method, id, _ := strings.Cut(principal, ":")
+		if id == "" || !knownMethod(method) {
+			return nil, fmt.Errorf("assignment %q must be of the form method:id with method one of %s",
+				principal, strings.Join(methods, ", "))
+		}
+		for _, role := range roles {
+			if _, ok := p.roles[role]; !ok {
+				return nil, fmt.Errorf("assignment for %q references undefined role %q", principal, role)
+			}
+		}
+	}
+	return p, nil
+}
+
+func knownMethod(method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles returns the roles held by principal: those it carries itself plus
+// any assigned to its method and ID in the configuration
+func (p *Policy) Roles(principal *auth.Principal) []string {
+	if principal == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var roles []string
+	for _, list := range [][]string{principal.Roles, p.assignments[principal.Method+":"+principal.ID]} {
+		for _, r := range list {
+			if !seen[r] {
+				seen[r] = true
+				roles = append(roles, r)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Allowed reports whether one of principal's roles grants permission.
+// Token scopes are not consulted, since any trusted issuer can mint them
+func (p *Policy) Allowed(principal *auth.Principal, permission string) bool {
+	if principal == nil {
+		return false
+	}
+	for _, role := range p.Roles(principal) {
+		perms := p.roles[role]
+		if perms[permission] || perms[Wildcard] {
+			return true
+		}
+	}
+	return false
+}
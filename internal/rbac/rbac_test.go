@@ -0,0 +1,88 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/auth"
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := NewPolicy(config.RBACConfig{
+		Roles: map[string][]string{
+			"admin":  {Wildcard},
+			"viewer": {"greetings:read"},
+			"editor": {"greetings:read", "greetings:write"},
+		},
+		Assignments: map[string][]string{
+			"oidc:alice":     {"admin"},
+			"api_key:shared": {"editor"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+	return p
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	p := testPolicy(t)
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		permission string
+		want       bool
+	}{
+		{"anonymous", nil, "greetings:read", false},
+		{"no roles", &auth.Principal{ID: "bob"}, "greetings:read", false},
+		{"role grants", &auth.Principal{ID: "bob", Roles: []string{"viewer"}}, "greetings:read", true},
+		{"role lacks", &auth.Principal{ID: "bob", Roles: []string{"viewer"}}, "greetings:write", false},
+		{"assigned wildcard", &auth.Principal{ID: "alice", Method: auth.MethodOIDC}, "models:admin", true},
+		{"assigned to another method", &auth.Principal{ID: "alice", Method: auth.MethodJWT}, "models:admin", false},
+		{"assigned api key", &auth.Principal{ID: "shared", Method: auth.MethodAPIKey}, "greetings:write", true},
+		{"jwt sub matching an api key", &auth.Principal{ID: "shared", Method: auth.MethodJWT}, "greetings:write", false},
+		{"scope does not grant", &auth.Principal{ID: "svc", Method: auth.MethodJWT, Scopes: []string{"greetings:write"}}, "greetings:write", false},
+		{"undeclared role", &auth.Principal{ID: "bob", Roles: []string{"ghost"}}, "greetings:read", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.Allowed(tc.principal, tc.permission); got != tc.want {
+				t.Errorf("Allowed() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicyRoles(t *testing.T) {
+	p := testPolicy(t)
+	got := p.Roles(&auth.Principal{ID: "alice", Method: auth.MethodOIDC, Roles: []string{"viewer", "admin"}})
+	if want := []string{"admin", "viewer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Roles() = %v, want %v", got, want)
+	}
+}
+
+func TestNewPolicyUndefinedRole(t *testing.T) {
+	_, err := NewPolicy(config.RBACConfig{
+		Roles:       map[string][]string{"viewer": {"greetings:read"}},
+		Assignments: map[string][]string{"oidc:alice": {"admin"}},
+	})
+	if err == nil {
+		t.Error("expected error for assignment to undefined role")
+	}
+}
+
+func TestNewPolicyAssignmentWithoutMethod(t *testing.T) {
+	for _, principal := range []string{"alice", "ldap:alice", "oidc:"} {
+		_, err := NewPolicy(config.RBACConfig{
+			Roles:       map[string][]string{"viewer": {"greetings:read"}},
+			Assignments: map[string][]string{principal: {"viewer"}},
+		})
+		if err == nil {
+			t.Errorf("expected error for assignment %q", principal)
+		}
+	}
+}
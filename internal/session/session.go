@@ -0,0 +1,263 @@
+// Package session provides server-side sessions for the browser UI
+package session
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// DefaultMaxSessions bounds the stored sessions when the configuration
+// leaves the limit unset
+const DefaultMaxSessions = 10000
+
+// gcInterval is how often Run drops expired sessions
+const gcInterval = time.Minute
+
+// Session holds per-browser values. It is safe for concurrent use
+type Session struct {
+	ID string
+
+	mu            sync.Mutex
+	values        map[string]any
+	expires       time.Time
+	authenticated bool
+}
+
+// Authenticate marks s as belonging to a signed-in user, which protects it
+// from eviction while anonymous sessions remain. Save s afterwards
+func (s *Session) Authenticate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authenticated = true
+}
+
+func (s *Session) isAuthenticated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authenticated
+}
+
+// Get returns the value stored under key
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores v under key
+func (s *Session) Set(key string, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = v
+}
+
+// Delete removes key from the session
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Manager stores sessions in memory and tracks them with a cookie. Once
+// it holds the maximum number of sessions, saving a new one evicts the
+// anonymous session saved longest ago, so that anonymous clients cannot
+// sign users out by flooding the store. Authenticated sessions are only
+// evicted when no anonymous one is left
+type Manager struct {
+	cookieName  string
+	ttl         time.Duration
+	secure      bool
+	maxSessions int
+
+	mu sync.Mutex
+	// anonymous and authenticated list sessions most recently saved
+	// first. Every save extends the expiry by the same TTL, so sessions
+	// also expire back to front
+	anonymous     *list.List // of *Session
+	authenticated *list.List // of *Session
+	sessions      map[string]*list.Element
+	now           func() time.Time
+}
+
+// NewManager returns a Manager configured from cfg
+func NewManager(cfg config.SessionConfig) *Manager {
+	maxSessions := cfg.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = DefaultMaxSessions
+	}
+	return &Manager{
+		cookieName:    cfg.CookieName,
+		ttl:           cfg.TTL,
+		secure:        cfg.Secure,
+		maxSessions:   maxSessions,
+		anonymous:     list.New(),
+		authenticated: list.New(),
+		sessions:      make(map[string]*list.Element),
+		now:           time.Now,
+	}
+}
+
+// Run drops expired sessions periodically until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			m.gc()
+			m.mu.Unlock()
+		}
+	}
+}
+
+type sessionKey struct{}
+
+// FromContext returns the session attached by Middleware
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Middleware attaches the caller's session to the request context. A new,
+// unsaved session is attached when the cookie is missing or expired
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.lookup(r)
+		if s == nil {
+			s = m.newSession()
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+	})
+}
+
+func (m *Manager) lookup(r *http.Request) *Session {
+	c, err := r.Cookie(m.cookieName)
+	if err != nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.sessions[c.Value]
+	if !ok {
+		return nil
+	}
+	s := el.Value.(*Session)
+	if !m.now().Before(s.expires) {
+		m.remove(el)
+		return nil
+	}
+	return s
+}
+
+func (m *Manager) newSession() *Session {
+	return &Session{ID: newID(), values: make(map[string]any)}
+}
+
+// Save persists s and sends its cookie. It must be called before the
+// response body is written
+func (m *Manager) Save(w http.ResponseWriter, s *Session) {
+	m.mu.Lock()
+	s.expires = m.now().Add(m.ttl)
+	m.delete(s.ID)
+	order := m.anonymous
+	if s.isAuthenticated() {
+		order = m.authenticated
+	}
+	m.sessions[s.ID] = order.PushFront(s)
+	for len(m.sessions) > m.maxSessions {
+		oldest := m.anonymous.Back()
+		if oldest == nil {
+			oldest = m.authenticated.Back()
+		}
+		m.remove(oldest)
+	}
+	m.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cookieName,
+		Value:    s.ID,
+		Path:     "/",
+		Expires:  s.expires,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Renew moves the values of s to a session with a fresh ID, preventing
+// session fixation when the privilege level changes (e.g. on login)
+func (m *Manager) Renew(w http.ResponseWriter, s *Session) *Session {
+	m.mu.Lock()
+	m.delete(s.ID)
+	m.mu.Unlock()
+
+	s.mu.Lock()
+	renewed := &Session{ID: newID(), values: s.values}
+	s.values = make(map[string]any)
+	s.mu.Unlock()
+
+	m.Save(w, renewed)
+	return renewed
+}
+
+// Destroy deletes s and clears the cookie
+func (m *Manager) Destroy(w http.ResponseWriter, s *Session) {
+	m.mu.Lock()
+	m.delete(s.ID)
+	m.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// gc drops expired sessions, starting from the oldest. Callers must hold
+// m.mu
+func (m *Manager) gc() {
+	now := m.now()
+	for _, order := range []*list.List{m.anonymous, m.authenticated} {
+		for el := order.Back(); el != nil && !now.Before(el.Value.(*Session).expires); el = order.Back() {
+			m.remove(el)
+		}
+	}
+}
+
+// delete drops the session with id, if stored. Callers must hold m.mu
+func (m *Manager) delete(id string) {
+	if el, ok := m.sessions[id]; ok {
+		m.remove(el)
+	}
+}
+
+// remove drops a stored session. Callers must hold m.mu
+func (m *Manager) remove(el *list.Element) {
+	// Removing an element from a list it does not belong to is a no-op
+	m.anonymous.Remove(el)
+	m.authenticated.Remove(el)
+	delete(m.sessions, el.Value.(*Session).ID)
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("session: reading random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
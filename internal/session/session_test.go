@@ -0,0 +1,172 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+func newTestManager() *Manager {
+	return NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour})
+}
+
+func TestManagerSaveAndLoad(t *testing.T) {
+	m := newTestManager()
+
+	set := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := FromContext(r.Context())
+		s.Set("greeting", "hello")
+		m.Save(w, s)
+	}))
+	rr := httptest.NewRecorder()
+	set.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" || !cookies[0].HttpOnly {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	var got any
+	get := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context()).Get("greeting")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	get.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "hello" {
+		t.Errorf("expected stored value, got %v", got)
+	}
+}
+
+func TestManagerExpiry(t *testing.T) {
+	m := newTestManager()
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	s := m.newSession()
+	s.Set("k", "v")
+	m.Save(httptest.NewRecorder(), s)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: s.ID})
+	if m.lookup(req) == nil {
+		t.Fatal("expected session before expiry")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if m.lookup(req) != nil {
+		t.Error("expected session to be expired")
+	}
+}
+
+func TestManagerRenewAndDestroy(t *testing.T) {
+	m := newTestManager()
+	s := m.newSession()
+	s.Set("k", "v")
+	m.Save(httptest.NewRecorder(), s)
+	oldID := s.ID
+
+	renewed := m.Renew(httptest.NewRecorder(), s)
+	if renewed.ID == oldID {
+		t.Fatal("expected a new session ID")
+	}
+	if renewed.Get("k") != "v" {
+		t.Error("expected values to carry over")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: oldID})
+	if m.lookup(req) != nil {
+		t.Error("old session ID should no longer be valid")
+	}
+
+	rr := httptest.NewRecorder()
+	m.Destroy(rr, renewed)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: renewed.ID})
+	if m.lookup(req) != nil {
+		t.Error("destroyed session should not be found")
+	}
+	if c := rr.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("expected cookie to be cleared, got %v", c)
+	}
+}
+
+func TestManagerEvictsOldest(t *testing.T) {
+	m := NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour, MaxSessions: 2})
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	var saved []*Session
+	for i := 0; i < 3; i++ {
+		s := m.newSession()
+		m.Save(httptest.NewRecorder(), s)
+		saved = append(saved, s)
+		now = now.Add(time.Second)
+		if i == 1 {
+			// Saving again keeps the first session from being evicted next
+			m.Save(httptest.NewRecorder(), saved[0])
+		}
+	}
+
+	for i, want := range []bool{true, false, true} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "sid", Value: saved[i].ID})
+		if got := m.lookup(req) != nil; got != want {
+			t.Errorf("session %d stored = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestManagerGC(t *testing.T) {
+	m := newTestManager()
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	old := m.newSession()
+	m.Save(httptest.NewRecorder(), old)
+	now = now.Add(30 * time.Minute)
+	fresh := m.newSession()
+	m.Save(httptest.NewRecorder(), fresh)
+
+	now = now.Add(45 * time.Minute)
+	m.mu.Lock()
+	m.gc()
+	_, oldKept := m.sessions[old.ID]
+	_, freshKept := m.sessions[fresh.ID]
+	n := len(m.sessions)
+	m.mu.Unlock()
+
+	if oldKept || !freshKept || n != 1 {
+		t.Errorf("after gc: old kept %v, fresh kept %v, %d stored", oldKept, freshKept, n)
+	}
+}
+
+func TestManagerKeepsAuthenticatedSessions(t *testing.T) {
+	m := NewManager(config.SessionConfig{CookieName: "sid", TTL: time.Hour, MaxSessions: 3})
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	user := m.newSession()
+	user.Authenticate()
+	m.Save(httptest.NewRecorder(), user)
+
+	// An anonymous client looping POST /hello saves a session every time
+	for i := 0; i < 100; i++ {
+		now = now.Add(time.Second)
+		m.Save(httptest.NewRecorder(), m.newSession())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: user.ID})
+	if m.lookup(req) != user {
+		t.Error("authenticated session was evicted by anonymous ones")
+	}
+	if n := len(m.sessions); n != 3 {
+		t.Errorf("%d sessions stored, want 3", n)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps greetings in memory. Its contents are lost on restart
+type MemoryStore struct {
+	mu        sync.RWMutex
+	greetings []Greeting
+	nextID    int64
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nextID: 1}
+}
+
+func (s *MemoryStore) Save(_ context.Context, g *Greeting) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g.ID = s.nextID
+	s.nextID++
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = time.Now().UTC()
+	}
+	s.greetings = append(s.greetings, *g)
+	return nil
+}
+
+func (s *MemoryStore) List(_ context.Context, opts ListOptions) ([]Greeting, error) {
+	if opts.Offset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// greetings is kept in insertion order; walk it backwards for newest first
+	var out []Greeting
+	for i := len(s.greetings) - 1 - opts.Offset; i >= 0; i-- {
+		if opts.Limit > 0 && len(out) == opts.Limit {
+			break
+		}
+		out = append(out, s.greetings[i])
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Count(_ context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.greetings), nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, g := range s.greetings {
+		if g.ID == id {
+			s.greetings = append(s.greetings[:i], s.greetings[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS greetings_created_at;
+DROP TABLE IF EXISTS greetings;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS greetings (
+	id         BIGSERIAL   PRIMARY KEY,
+	name       TEXT        NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS greetings_created_at ON greetings (created_at);
@@ -0,0 +1,7 @@
+-- IF NOT EXISTS adopts databases created before migrations were introduced
+CREATE TABLE IF NOT EXISTS greetings (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	name       TEXT    NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS greetings_created_at ON greetings (created_at);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS greetings_created_at ON greetings (created_at);
@@ -0,0 +1,2 @@
+-- Greetings are listed by id, which the primary key already indexes
+DROP INDEX IF EXISTS greetings_created_at;
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/migrate"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgresStore persists greetings in a PostgreSQL database
+type PostgresStore struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+// OpenPostgres opens a connection pool configured by cfg and checks that
+// the database is reachable
+func OpenPostgres(ctx context.Context, cfg config.PostgresConfig) (*PostgresStore, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("postgres dsn is empty")
+	}
+	db, err := sql.Open("pgx", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	s := &PostgresStore{db: db, queryTimeout: cfg.QueryTimeout}
+	pingCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	return s, nil
+}
+
+// withTimeout derives the context for a single query. The caller's
+// deadline, usually the request's, still applies if it is sooner
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Migrator returns a migrator for the store's database
+func (s *PostgresStore) Migrator() (*migrate.Migrator, error) {
+	migrations, err := Migrations(DriverPostgres)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(s.db, migrate.Postgres{}, migrations), nil
+}
+
+func (s *PostgresStore) Save(ctx context.Context, g *Greeting) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if g.CreatedAt.IsZero() {
+		// PostgreSQL stores microseconds; truncate so the caller sees what
+		// List will return
+		g.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO greetings (name, created_at) VALUES ($1, $2) RETURNING id`,
+		g.Name, g.CreatedAt).Scan(&g.ID)
+	if err != nil {
+		return fmt.Errorf("saving greeting: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) List(ctx context.Context, opts ListOptions) ([]Greeting, error) {
+	if opts.Offset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// A NULL limit means no limit in PostgreSQL
+	var limit sql.NullInt64
+	if opts.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(opts.Limit), Valid: true}
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, created_at FROM greetings ORDER BY id DESC LIMIT $1 OFFSET $2`,
+		limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing greetings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Greeting
+	for rows.Next() {
+		var g Greeting
+		if err := rows.Scan(&g.ID, &g.Name, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("listing greetings: %w", err)
+		}
+		g.CreatedAt = g.CreatedAt.UTC()
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStore) Count(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM greetings`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting greetings: %w", err)
+	}
+	return n, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM greetings WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting greeting: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting greeting: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the connection pool, waiting for queries in progress
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
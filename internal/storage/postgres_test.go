@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+)
+
+// postgresDSNEnv names a throwaway database for the integration tests. They
+// drop and recreate the schema, so never point it at real data
+const postgresDSNEnv = "IRIS_TEST_POSTGRES_DSN"
+
+func openTestPostgres(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+	cfg := config.DefaultConfig().Storage.Postgres
+	cfg.DSN = dsn
+
+	ctx := context.Background()
+	s, err := OpenPostgres(ctx, cfg)
+	if err != nil {
+		t.Fatalf("OpenPostgres() failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator() failed: %v", err)
+	}
+	if _, err := m.Down(ctx, 1<<10); err != nil {
+		t.Fatalf("resetting schema failed: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("migrating failed: %v", err)
+	}
+	return s
+}
+
+func TestPostgresStore(t *testing.T) {
+	s := openTestPostgres(t)
+	testGreetingStore(t, s)
+
+	g := &Greeting{Name: "ünïcode"}
+	if err := s.Save(context.Background(), g); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	got, err := s.List(context.Background(), ListOptions{Limit: 1})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("List() = %v, %v", got, err)
+	}
+	if got[0] != *g {
+		t.Errorf("round trip = %+v, want %+v", got[0], *g)
+	}
+}
+
+func TestPostgresStoreRespectsContext(t *testing.T) {
+	s := openTestPostgres(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Save(ctx, &Greeting{Name: "late"}); err == nil {
+		t.Error("Save() with cancelled context succeeded")
+	}
+
+	s.queryTimeout = 50 * time.Millisecond
+	qctx, cancel := s.withTimeout(context.Background())
+	defer cancel()
+	if _, err := s.db.ExecContext(qctx, "SELECT pg_sleep(1)"); err == nil {
+		t.Error("query outlived the query timeout")
+	}
+}
+
+func TestPostgresConcurrentMigrations(t *testing.T) {
+	s := openTestPostgres(t)
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator() failed: %v", err)
+	}
+	if _, err := m.Down(context.Background(), 1<<10); err != nil {
+		t.Fatalf("resetting schema failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	applied := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := m.Up(context.Background())
+			if err != nil {
+				t.Errorf("Up() failed: %v", err)
+			}
+			mu.Lock()
+			applied += len(done)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	migrations, _ := Migrations(DriverPostgres)
+	if applied != len(migrations) {
+		t.Errorf("applied %d migrations in total, want %d", applied, len(migrations))
+	}
+}
+
+func TestOpenPostgresErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := OpenPostgres(ctx, config.PostgresConfig{}); err == nil {
+		t.Error("expected error for empty dsn")
+	}
+
+	cfg := config.PostgresConfig{
+		DSN:          "postgres://iris@127.0.0.1:1/iris?connect_timeout=1",
+		QueryTimeout: 2 * time.Second,
+	}
+	if _, err := OpenPostgres(ctx, cfg); err == nil {
+		t.Error("expected error for unreachable server")
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/migrate"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore persists greetings in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (or creates) the database at path. The schema is managed
+// by the migrations returned from Migrator
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite path is empty")
+	}
+	// WAL lets the greetings page read while a submission is being written
+	dsn := "file:" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	// SQLite serializes writers; a single connection avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Migrator returns a migrator for the store's database
+func (s *SQLiteStore) Migrator() (*migrate.Migrator, error) {
+	migrations, err := Migrations(DriverSQLite)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(s.db, migrate.SQLite{}, migrations), nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, g *Greeting) error {
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO greetings (name, created_at) VALUES (?, ?)`,
+		g.Name, g.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("saving greeting: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("saving greeting: %w", err)
+	}
+	g.ID = id
+	return nil
+}
+
+func (s *SQLiteStore) List(ctx context.Context, opts ListOptions) ([]Greeting, error) {
+	if opts.Offset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, created_at FROM greetings ORDER BY id DESC LIMIT ? OFFSET ?`,
+		limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing greetings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Greeting
+	for rows.Next() {
+		var g Greeting
+		var created int64
+		if err := rows.Scan(&g.ID, &g.Name, &created); err != nil {
+			return nil, fmt.Errorf("listing greetings: %w", err)
+		}
+		g.CreatedAt = time.Unix(0, created).UTC()
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) Count(ctx context.Context) (int, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM greetings`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting greetings: %w", err)
+	}
+	return n, nil
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM greetings WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting greeting: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting greeting: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
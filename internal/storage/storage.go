@@ -0,0 +1,138 @@
+// Package storage persists the greetings submitted through the form
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/migrate"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+// Supported storage drivers
+const (
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// ErrNotFound is returned when a greeting does not exist
+var ErrNotFound = errors.New("greeting not found")
+
+// Greeting is a single submission of the hello form
+type Greeting struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListOptions paginates List results
+type ListOptions struct {
+	Limit int
+	// Offset skips the newest greetings; List rejects negative values
+	Offset int
+}
+
+// ErrInvalidOffset is returned by List for a negative offset
+var ErrInvalidOffset = errors.New("negative list offset")
+
+// GreetingStore persists greetings. List returns the newest greetings first
+type GreetingStore interface {
+	// Save stores g and fills in its ID and, if unset, CreatedAt
+	Save(ctx context.Context, g *Greeting) error
+	List(ctx context.Context, opts ListOptions) ([]Greeting, error)
+	Count(ctx context.Context) (int, error)
+	// Delete removes the greeting with id or returns ErrNotFound
+	Delete(ctx context.Context, id int64) error
+	Close() error
+}
+
+// Migrations returns the schema migrations embedded for driver
+func Migrations(driver string) ([]migrate.Migration, error) {
+	return migrate.Load(migrationsFS, "migrations/"+driver)
+}
+
+// sqlStore is a GreetingStore whose schema is managed by migrations
+type sqlStore interface {
+	GreetingStore
+	Migrator() (*migrate.Migrator, error)
+}
+
+// openSQL opens the SQL database selected by cfg.Driver without touching
+// its schema
+func openSQL(ctx context.Context, cfg config.StorageConfig) (sqlStore, error) {
+	switch cfg.Driver {
+	case DriverSQLite:
+		s, err := OpenSQLite(cfg.SQLite.Path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case DriverPostgres:
+		s, err := OpenPostgres(ctx, cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case DriverMemory, "":
+		return nil, errors.New("the memory storage driver has no schema to migrate")
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
+
+// Open returns the store selected by cfg.Driver. SQL stores are migrated
+// when cfg.AutoMigrate is set; otherwise Open fails if the schema is not
+// up to date
+func Open(ctx context.Context, cfg config.StorageConfig) (GreetingStore, error) {
+	if cfg.Driver == DriverMemory || cfg.Driver == "" {
+		return NewMemoryStore(), nil
+	}
+	s, err := openSQL(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.Migrator()
+	if err == nil {
+		err = prepareSchema(ctx, m, cfg.AutoMigrate)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func prepareSchema(ctx context.Context, m *migrate.Migrator, auto bool) error {
+	if !auto {
+		if err := m.CheckCurrent(ctx); err != nil {
+			return fmt.Errorf("%w (run \"migrate up\" or enable storage.auto_migrate)", err)
+		}
+		return nil
+	}
+	if _, err := m.Up(ctx); err != nil {
+		return fmt.Errorf("migrating database: %w", err)
+	}
+	return nil
+}
+
+// OpenMigrator opens the database configured in cfg for schema management.
+// The returned Closer releases the database
+func OpenMigrator(ctx context.Context, cfg config.StorageConfig) (*migrate.Migrator, io.Closer, error) {
+	s, err := openSQL(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := s.Migrator()
+	if err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+	return m, s, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Elenetta17/iris-web-service/internal/config"
+	"github.com/Elenetta17/iris-web-service/internal/migrate"
+)
+
+// testGreetingStore exercises the GreetingStore contract shared by all
+// implementations
+func testGreetingStore(t *testing.T, s GreetingStore) {
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		g := &Greeting{Name: fmt.Sprintf("user-%d", i)}
+		if err := s.Save(ctx, g); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		if g.ID == 0 || g.CreatedAt.IsZero() {
+			t.Fatalf("Save() did not fill in ID and CreatedAt: %+v", g)
+		}
+	}
+
+	if n, err := s.Count(ctx); err != nil || n != 5 {
+		t.Fatalf("Count() = %d, %v; want 5", n, err)
+	}
+
+	page, err := s.List(ctx, ListOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(page) != 2 || page[0].Name != "user-4" || page[1].Name != "user-3" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	all, err := s.List(ctx, ListOptions{})
+	if err != nil || len(all) != 5 {
+		t.Fatalf("List() without limit = %d items, %v; want 5", len(all), err)
+	}
+
+	if past, err := s.List(ctx, ListOptions{Limit: 10, Offset: 10}); err != nil || len(past) != 0 {
+		t.Errorf("List() past the end = %+v, %v; want empty", past, err)
+	}
+	if _, err := s.List(ctx, ListOptions{Limit: 10, Offset: -13}); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("List() with negative offset = %v, want ErrInvalidOffset", err)
+	}
+
+	if err := s.Delete(ctx, all[0].ID); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if err := s.Delete(ctx, all[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() = %v, want ErrNotFound", err)
+	}
+	if n, _ := s.Count(ctx); n != 4 {
+		t.Errorf("Count() after delete = %d, want 4", n)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testGreetingStore(t, NewMemoryStore())
+}
+
+func openMigratedSQLite(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() failed: %v", err)
+	}
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator() failed: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrating failed: %v", err)
+	}
+	return s
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iris.db")
+	s := openMigratedSQLite(t, path)
+	testGreetingStore(t, s)
+	s.Close()
+
+	// Data survives reopening the database
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("reopening database failed: %v", err)
+	}
+	defer s.Close()
+	if n, err := s.Count(context.Background()); err != nil || n != 4 {
+		t.Errorf("Count() after reopen = %d, %v; want 4", n, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	s, err := Open(ctx, config.StorageConfig{Driver: DriverMemory})
+	if err != nil {
+		t.Fatalf("Open(memory) failed: %v", err)
+	}
+	if _, ok := s.(*MemoryStore); !ok {
+		t.Errorf("expected *MemoryStore, got %T", s)
+	}
+
+	sqlite := config.StorageConfig{
+		Driver: DriverSQLite,
+		SQLite: config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "iris.db")},
+	}
+	if _, err := Open(ctx, sqlite); !errors.Is(err, migrate.ErrPending) {
+		t.Fatalf("Open(sqlite) without migrations = %v, want ErrPending", err)
+	}
+
+	sqlite.AutoMigrate = true
+	s, err = Open(ctx, sqlite)
+	if err != nil {
+		t.Fatalf("Open(sqlite) with auto_migrate failed: %v", err)
+	}
+	s.Close()
+
+	sqlite.AutoMigrate = false
+	s, err = Open(ctx, sqlite)
+	if err != nil {
+		t.Fatalf("Open(sqlite) on migrated database failed: %v", err)
+	}
+	s.Close()
+
+	if _, err := Open(ctx, config.StorageConfig{Driver: "oracle"}); err == nil {
+		t.Error("expected error for unknown driver")
+	}
+}
+
+func TestMigrationsEmbedded(t *testing.T) {
+	migrations, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatalf("Migrations() failed: %v", err)
+	}
+	for _, m := range migrations {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}